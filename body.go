@@ -0,0 +1,150 @@
+package gohttp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// DefaultBodyReplayLimit is the max size of a non-seekable request body `gohttp`
+// buffers in memory, so that it can be sent again on retries and redirects.
+const DefaultBodyReplayLimit = 10 << 20
+
+// errBodyNotReplayable is returned when request body has been consumed and can not be read again.
+var errBodyNotReplayable = errors.New("gohttp: request body can not be replayed")
+
+// setReplayableBody makes sure request body can be read again from the beginning,
+// by setting `GetBody` of the request.
+//
+// `http.NewRequest` already does this for `bytes.Buffer`, `bytes.Reader` and `strings.Reader`,
+// which covers `JSON`, `Form` and `File` payloads. For other readers:
+// - seekable readers with `ReadAt` (like `os.File`) are read by independent section readers,
+//   from the position where reading starts
+// - other readers are buffered in memory, if their size does not exceed `limit`.
+//   Larger streams are sent as is, and the request is marked as non-retryable.
+func setReplayableBody(req *http.Request, body io.Reader, limit int64) error {
+	if body == nil || req.GetBody != nil {
+		return nil
+	}
+
+	if section, ok := sectionOf(body); ok {
+		// `GetBody` can be called while the previous body is still being read, like on redirects,
+		// every reader has its own offset. Transport does not close the source, see `seekableBodyCloser`.
+		req.ContentLength = section.Size()
+		req.Body = ioutil.NopCloser(section)
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(io.NewSectionReader(section, 0, section.Size())), nil
+		}
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > limit {
+		// too large to keep in memory, send what has been read followed by the rest of the stream.
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), body), req.Body}
+		return nil
+	}
+
+	if closer, ok := body.(io.Closer); ok {
+		closer.Close()
+	}
+	req.ContentLength = int64(len(data))
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	return nil
+}
+
+// sectionOf returns a section reader of body from its current position to the end,
+// if body supports `ReadAt` and `Seek`.
+func sectionOf(body io.Reader) (*io.SectionReader, bool) {
+	ra, ok := body.(io.ReaderAt)
+	if !ok {
+		return nil, false
+	}
+	seeker, ok := body.(io.Seeker)
+	if !ok {
+		return nil, false
+	}
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, false
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, false
+	}
+	if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+		return nil, false
+	}
+	return io.NewSectionReader(ra, offset, end-offset), true
+}
+
+// seekableBodyCloser returns the closer of body which is read by section readers. Transport only
+// closes the readers, so the source is closed by gohttp when request is done, like other bodies.
+func seekableBodyCloser(body io.Reader) (io.Closer, bool) {
+	_, isReaderAt := body.(io.ReaderAt)
+	_, isSeeker := body.(io.Seeker)
+	closer, isCloser := body.(io.Closer)
+	return closer, isReaderAt && isSeeker && isCloser
+}
+
+// canReplayBody reports whether request can be sent once more with the identical body.
+func canReplayBody(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewindBody resets request body to the beginning, so that request can be sent again.
+func rewindBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody == nil {
+		return errBodyNotReplayable
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+// requestBody returns a copy of request body, the request body is rewound afterwards.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, errBodyNotReplayable
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return data, rewindBody(req)
+}
+
+// BodyReplayLimit sets the max size of a non-seekable request body that will be buffered
+// in memory. Buffered body is sent again with every retry attempt and 307/308 redirect.
+// Body larger than the limit is streamed, and the request will not be retried.
+//
+// Default value is `DefaultBodyReplayLimit`.
+func (c *Client) BodyReplayLimit(limit int64) *Client {
+	c.bodyReplayLimit = limit
+	return c
+}
@@ -0,0 +1,154 @@
+package gohttp_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// onlyReader hides any other interface of the wrapped reader, like `io.Seeker`
+type onlyReader struct {
+	io.Reader
+}
+
+// newFlakyServer returns a test server that fails `failures` times with 503,
+// and records every request body it receives.
+func newFlakyServer(failures int, bodies *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		*bodies = append(*bodies, string(data))
+		if len(*bodies) <= failures {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(data)
+	}))
+}
+
+func retryOn503() *gohttp.RetryPolicy {
+	return &gohttp.RetryPolicy{
+		MaxAttempts: 3,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	}
+}
+
+func TestRetryReplaysJSONBody(t *testing.T) {
+	assert := assert.New(t)
+
+	bodies := []string{}
+	ts := newFlakyServer(2, &bodies)
+	defer ts.Close()
+
+	resp, err := gohttp.New().RetryPolicy(retryOn503()).JSON(`{"name":"gohttp"}`).Post(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal([]string{`{"name":"gohttp"}`, `{"name":"gohttp"}`, `{"name":"gohttp"}`}, bodies)
+}
+
+func TestRetryReplaysStreamBody(t *testing.T) {
+	assert := assert.New(t)
+
+	bodies := []string{}
+	ts := newFlakyServer(1, &bodies)
+	defer ts.Close()
+
+	body := onlyReader{strings.NewReader("streaming payload")}
+	resp, err := gohttp.New().RetryPolicy(retryOn503()).Body(body).Post(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal([]string{"streaming payload", "streaming payload"}, bodies)
+}
+
+func TestRetryReplaysSeekableBody(t *testing.T) {
+	assert := assert.New(t)
+
+	bodies := []string{}
+	ts := newFlakyServer(1, &bodies)
+	defer ts.Close()
+
+	f, err := os.Open("./LICENSE")
+	assert.NoError(err)
+	defer f.Close()
+	license, _ := ioutil.ReadFile("./LICENSE")
+
+	resp, err := gohttp.New().RetryPolicy(retryOn503()).Body(f).Put(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal([]string{string(license), string(license)}, bodies)
+	assert.True(errors.Is(f.Close(), os.ErrClosed), "file should be closed when request is done")
+}
+
+func TestRetryStopsOnLargeStreamBody(t *testing.T) {
+	assert := assert.New(t)
+
+	bodies := []string{}
+	ts := newFlakyServer(1, &bodies)
+	defer ts.Close()
+
+	body := onlyReader{strings.NewReader("a payload larger than limit")}
+	resp, err := gohttp.New().RetryPolicy(retryOn503()).BodyReplayLimit(4).Body(body).Post(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusServiceUnavailable, resp.StatusCode, "large stream should not be retried")
+	assert.Equal([]string{"a payload larger than limit"}, bodies)
+}
+
+func TestRedirectReplaysBody(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer ts.Close()
+
+	body := onlyReader{strings.NewReader("redirected payload")}
+	resp, err := gohttp.New().Body(body).Path("/old").Post(ts.URL)
+	assert.NoError(err)
+	data, _ := resp.AsString()
+	assert.Equal("redirected payload", data)
+}
+
+func TestSeekableBodyReadersAreIndependent(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := os.Open("./LICENSE")
+	assert.NoError(err)
+	defer f.Close()
+	license, _ := ioutil.ReadFile("./LICENSE")
+	// reading starts from the current position
+	f.Seek(8, io.SeekStart)
+
+	var first, second string
+	transport := gohttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		head := make([]byte, 4)
+		io.ReadFull(req.Body, head)
+
+		// a body from GetBody does not move the offset of the one being read
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		data, _ := ioutil.ReadAll(body)
+		second = string(data)
+		rest, _ := ioutil.ReadAll(req.Body)
+		first = string(head) + string(rest)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+
+	_, err = gohttp.New().Transport(transport).Body(f).Put("http://example.com/upload")
+	assert.NoError(err)
+	assert.Equal(string(license[8:]), first)
+	assert.Equal(string(license[8:]), second)
+}
@@ -0,0 +1,98 @@
+package gohttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+func TestDoContextCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := gohttp.New().DoContext(ctx, "GET", ts.URL)
+	assert.Equal(context.Canceled, err, "context error should be returned as is")
+}
+
+func TestContextDeadline(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := gohttp.New().Context(ctx)
+	_, err := c.New().Get(ts.URL)
+	assert.Equal(context.DeadlineExceeded, err, "cloned client should inherit context")
+}
+
+func TestContextAbortsRetryWait(t *testing.T) {
+	assert := assert.New(t)
+
+	tried := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tried++
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	policy := &gohttp.RetryPolicy{
+		MaxAttempts:     3,
+		StatusCodes:     []int{http.StatusServiceUnavailable},
+		InitialInterval: time.Second,
+	}
+	start := time.Now()
+	_, err := gohttp.New().Context(ctx).RetryPolicy(policy).Get(ts.URL)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.Equal(1, tried)
+	assert.True(time.Since(start) < time.Second, "should not wait for the whole backoff")
+}
+
+func TestContextAbortsBodyRead(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	// debug mode dumps response, which reads the whole body before cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := gohttp.New().Debug(false).Context(ctx).Get(ts.URL)
+	assert.NoError(err)
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = resp.AsBytes()
+	assert.Error(err, "reading body should be aborted by context")
+}
@@ -0,0 +1,189 @@
+package gohttp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// netscapeHeader is the first line of a Netscape cookie file, curl and wget write it as well.
+const netscapeHeader = "# Netscape HTTP Cookie File"
+
+// httpOnlyPrefix marks HttpOnly cookies in Netscape cookie file, it is a curl extension.
+const httpOnlyPrefix = "#HttpOnly_"
+
+// errNoSession is returned when saving or loading cookies of a client without a `Session` jar.
+var errNoSession = errors.New("gohttp: cookie jar of client is not a *gohttp.Session")
+
+// Save writes all unexpired cookies in the session to file.
+// Cookies are written in JSON if the file name ends with `.json`, otherwise in
+// the Netscape `cookies.txt` format used by curl and wget.
+func (s *Session) Save(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if isJSONFile(path) {
+		err = s.writeJSON(f)
+	} else {
+		err = s.writeNetscape(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads cookies from file written by `Save`, or by curl and wget, and adds them to the session.
+// The format is chosen by file name the same way as `Save`. Expired cookies are skipped.
+func (s *Session) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if isJSONFile(path) {
+		return s.readJSON(f)
+	}
+	return s.readNetscape(f)
+}
+
+func isJSONFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// writeNetscape writes cookies in Netscape format, each line is a cookie with tab-separated fields:
+//    domain  include-subdomains  path  secure  expires  name  value
+func (s *Session) writeNetscape(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n# This file was generated by gohttp, edit at your own risk.\n\n", netscapeHeader)
+
+	for _, entry := range s.cookieEntries() {
+		domain := entry.Domain
+		if !entry.HostOnly {
+			domain = "." + domain
+		}
+		if entry.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		expires := int64(0)
+		if !entry.Expires.IsZero() {
+			expires = entry.Expires.Unix()
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(!entry.HostOnly), entry.Path, netscapeBool(entry.Secure),
+			expires, entry.Name, entry.Value)
+	}
+	return bw.Flush()
+}
+
+func (s *Session) readNetscape(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := false
+		if strings.HasPrefix(line, httpOnlyPrefix) {
+			httpOnly = true
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			// cookie with empty value, trailing tab may be trimmed by editors
+			fields = append(fields, "")
+		}
+		if len(fields) != 7 {
+			return fmt.Errorf("gohttp: invalid cookie file line %d: expect 7 fields, got %d", lineNo, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("gohttp: invalid cookie file line %d: %v", lineNo, err)
+		}
+
+		entry := &cookieEntry{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+			Name:     fields[5],
+			Value:    fields[6],
+		}
+		if expires > 0 {
+			entry.Expires = time.Unix(expires, 0)
+		}
+		s.addCookieEntry(entry)
+	}
+	return scanner.Err()
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+func (s *Session) writeJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s.cookieEntries())
+}
+
+func (s *Session) readJSON(r io.Reader) error {
+	entries := []*cookieEntry{}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		s.addCookieEntry(entry)
+	}
+	return nil
+}
+
+// SaveCookies saves cookies in the client's session to file, see `Session.Save` for file formats.
+// An error is returned if the cookie jar of client is not a `*Session`.
+func (c *Client) SaveCookies(path string) error {
+	session, ok := c.jar.(*Session)
+	if !ok {
+		return errNoSession
+	}
+	return session.Save(path)
+}
+
+// LoadCookies loads cookies from file into the client's session, see `Session.Load` for file formats.
+// A new session is created if the client has no cookie jar yet, and an error is returned
+// if the cookie jar of client is not a `*Session`.
+//
+// Usage:
+//    c := gohttp.New()
+//    if err := c.LoadCookies("cookies.txt"); err != nil {
+//        c.New().Form(login).Post("https://somesite.com/login")
+//        c.SaveCookies("cookies.txt")
+//    }
+func (c *Client) LoadCookies(path string) error {
+	if c.jar == nil {
+		c.jar = NewSession()
+	}
+	session, ok := c.jar.(*Session)
+	if !ok {
+		return errNoSession
+	}
+	return session.Load(path)
+}
@@ -0,0 +1,136 @@
+package gohttp_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// newCookieServer sets a few cookies on `/login`, and echoes cookies received on other paths.
+func newCookieServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true})
+			http.SetCookie(w, &http.Cookie{Name: "lang", Value: "en", Path: "/", MaxAge: 3600})
+			http.SetCookie(w, &http.Cookie{Name: "gone", Value: "x", Path: "/", MaxAge: -1})
+			return
+		}
+		names := []string{}
+		for _, cookie := range r.Cookies() {
+			names = append(names, cookie.Name+"="+cookie.Value)
+		}
+		sort.Strings(names)
+		fmt.Fprint(w, strings.Join(names, ";"))
+	}))
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gohttp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSaveAndLoadCookies(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newCookieServer()
+	defer ts.Close()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"cookies.txt", "cookies.json"} {
+		path := filepath.Join(dir, name)
+
+		c := gohttp.New().URL(ts.URL).CookieJar(gohttp.NewSession())
+		_, err := c.New().Path("/login").Get()
+		assert.NoError(err)
+		assert.NoError(c.SaveCookies(path))
+
+		// a new process loads cookies from file
+		loaded := gohttp.New().URL(ts.URL)
+		assert.NoError(loaded.LoadCookies(path))
+		resp, err := loaded.New().Path("/profile").Get()
+		assert.NoError(err)
+		data, _ := resp.AsString()
+		assert.Equal("lang=en;session=abc", data, "cookies loaded from %s should be sent", name)
+	}
+}
+
+func TestSaveCookiesNetscapeFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newCookieServer()
+	defer ts.Close()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	c := gohttp.New().URL(ts.URL).CookieJar(gohttp.NewSession())
+	c.New().Path("/login").Get()
+
+	path := filepath.Join(dir, "cookies.txt")
+	assert.NoError(c.SaveCookies(path))
+	data, _ := ioutil.ReadFile(path)
+	content := string(data)
+
+	assert.True(strings.HasPrefix(content, "# Netscape HTTP Cookie File\n"))
+	assert.Contains(content, "#HttpOnly_127.0.0.1\tFALSE\t/\tFALSE\t0\tsession\tabc\n")
+	assert.Contains(content, "127.0.0.1\tFALSE\t/\tFALSE\t")
+	assert.NotContains(content, "gone", "deleted cookie should not be saved")
+}
+
+func TestLoadCurlCookies(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Hour).Unix()
+	lines := []string{
+		"# Netscape HTTP Cookie File",
+		"# https://curl.se/docs/http-cookies.html",
+		"",
+		fmt.Sprintf(".example.com\tTRUE\t/\tFALSE\t%d\tdomain\tall", future),
+		fmt.Sprintf("#HttpOnly_www.example.com\tFALSE\t/api\tTRUE\t%d\tsecure\ts", future),
+		fmt.Sprintf("www.example.com\tFALSE\t/\tFALSE\t%d\texpired\told", past),
+		"www.example.com\tFALSE\t/\tFALSE\t0\tsession\tyes",
+	}
+	path := filepath.Join(dir, "cookies.txt")
+	ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+
+	c := gohttp.New()
+	assert.NoError(c.LoadCookies(path))
+
+	cookieNames := func(url string) []string {
+		names := []string{}
+		for _, cookie := range c.Cookies(url) {
+			names = append(names, cookie.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	assert.Equal([]string{"domain"}, cookieNames("http://sub.example.com/"))
+	assert.Equal([]string{"domain", "session"}, cookieNames("http://www.example.com/"))
+	assert.Equal([]string{"domain", "secure", "session"}, cookieNames("https://www.example.com/api/users"))
+	assert.Equal([]string{"domain", "session"}, cookieNames("http://www.example.com/api/users"),
+		"secure cookie should not be sent over http")
+}
+
+func TestSaveCookiesWithoutSession(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Error(gohttp.New().SaveCookies("cookies.txt"))
+}
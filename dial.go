@@ -0,0 +1,137 @@
+package gohttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// dialFunc is the signature of `http.Transport.DialContext`.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dialSettings are proxy settings of a client, and connection options that need a custom dialer,
+// like unix socket and address overrides. Like `tlsSettings`, it is shared by cloned clients, and replaced by a modified
+// copy when any option changes.
+type dialSettings struct {
+	proxy         *url.URL
+	proxyRules    []proxyRule
+	noProxy       []hostPattern
+	proxyAuth     *url.Userinfo
+	connectHeader http.Header
+	unixSocket    string
+	resolve       map[string]string
+	connectTo     []connectRule
+
+	// err is the first invalid option, it is returned by requests
+	err error
+
+	// transports are copies of base transports with the custom dialer, they are built on first use.
+	// Clones with different TLS options share the settings with different base transports.
+	mu         sync.Mutex
+	transports map[*http.Transport]*http.Transport
+}
+
+// dialOptions returns a copy of dial settings for the client to modify.
+func (c *Client) dialOptions() *dialSettings {
+	s := &dialSettings{connectHeader: make(http.Header), resolve: make(map[string]string)}
+	if c.dial != nil {
+		s.proxy = c.dial.proxy
+		s.proxyRules = append([]proxyRule(nil), c.dial.proxyRules...)
+		s.noProxy = append([]hostPattern(nil), c.dial.noProxy...)
+		s.proxyAuth = c.dial.proxyAuth
+		s.connectHeader = c.dial.connectHeader.Clone()
+		s.unixSocket = c.dial.unixSocket
+		for hostPort, addr := range c.dial.resolve {
+			s.resolve[hostPort] = addr
+		}
+		s.connectTo = append([]connectRule(nil), c.dial.connectTo...)
+		s.err = c.dial.err
+	}
+	c.dial = s
+	return s
+}
+
+// setErr keeps the first invalid option error.
+func (s *dialSettings) setErr(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// transportFor returns a copy of base transport which resolves proxies and dials with the settings.
+// The copy is reused by all requests with the same base transport.
+func (s *dialSettings) transportFor(base *http.Transport) (*http.Transport, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.transports[base]; ok {
+		return t, nil
+	}
+	t := base.Clone()
+	t.Proxy = s.proxyFunc(base.Proxy)
+	if len(s.connectHeader) > 0 {
+		t.ProxyConnectHeader = s.connectHeader.Clone()
+	}
+	t.DialContext = s.dialContext(baseDialer(base))
+	if s.transports == nil {
+		s.transports = make(map[*http.Transport]*http.Transport)
+	}
+	s.transports[base] = t
+	return t, nil
+}
+
+// dialContext returns the dial function applying the settings, `forward` makes the actual connections.
+func (s *dialSettings) dialContext(forward dialFunc) dialFunc {
+	if s.unixSocket != "" {
+		return dialUnix(s.unixSocket, forward)
+	}
+
+	// SOCKS proxies are dialed here, HTTP proxies are handled by transport
+	socksDialers := make(map[*url.URL]*socksDialer)
+	httpProxies := make(map[string]bool)
+	for _, proxy := range s.proxies() {
+		if isSOCKSProxy(proxy) {
+			socksDialers[proxy] = newSOCKSDialer(s.withAuth(proxy), forward)
+		} else {
+			httpProxies[canonicalAddr(proxy)] = true
+		}
+	}
+	if len(socksDialers) == 0 && len(s.resolve) == 0 && len(s.connectTo) == 0 {
+		return forward
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// transport dials HTTP proxies themselves, never through another proxy
+		if httpProxies[addr] {
+			return forward(ctx, network, addr)
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		// proxy is chosen by the original host, overrides change where it connects to
+		target := s.overrideAddr(host, port)
+		if proxy, _ := s.proxyForHost(host, port); proxy != nil && socksDialers[proxy] != nil {
+			return socksDialers[proxy].DialContext(ctx, network, target)
+		}
+		return forward(ctx, network, target)
+	}
+}
+
+// baseDialer returns the dial function of transport, or a dialer with the defaults of `http.DefaultTransport`.
+func baseDialer(t *http.Transport) dialFunc {
+	if t.DialContext != nil {
+		return t.DialContext
+	}
+	return (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+}
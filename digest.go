@@ -0,0 +1,325 @@
+package gohttp
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestAlgorithms are the supported digest algorithms, in the order of preference.
+var digestAlgorithms = []string{"SHA-512-256", "SHA-256", "MD5"}
+
+// digestChallenge is the `WWW-Authenticate` challenge of digest authentication, see RFC 7616 section 3.3.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string // upper case, without `-sess` suffix
+	sess      bool
+	qop       []string
+	stale     bool
+	userhash  bool
+}
+
+// digestAuth computes digest `Authorization` header for requests, it is shared by clones of a client,
+// so that the challenge is reused and nonce count keeps increasing across requests.
+type digestAuth struct {
+	username string
+	password string
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	nc        uint32
+}
+
+// DigestAuth sets username and password for HTTP digest access authentication, see RFC 7616.
+//
+// The first request is sent without credentials, when server answers 401 Unauthorized with a digest
+// challenge, the response is computed and the request is sent again. The challenge is remembered,
+// so following requests of the client and its clones are authorized without the extra round trip.
+// MD5, SHA-256 and SHA-512-256 algorithms, their `-sess` variants, and both `auth` and `auth-int`
+// quality of protection are supported. Non-ASCII usernames are sent as `username*`, or hashed
+// if server asks for `userhash`.
+//
+// Usage:
+//    gohttp.New().DigestAuth("admin", "password").Get("http://192.168.1.10/status")
+func (c *Client) DigestAuth(username, password string) *Client {
+	c.digest = &digestAuth{username: username, password: password}
+	return c
+}
+
+// authorize sets `Authorization` header if a challenge has been received,
+// it returns whether the header is set.
+func (d *digestAuth) authorize(req *http.Request) (bool, error) {
+	d.mu.Lock()
+	challenge := d.challenge
+	if challenge == nil {
+		d.mu.Unlock()
+		return false, nil
+	}
+	d.nc++
+	nc := d.nc
+	d.mu.Unlock()
+
+	auth, err := d.authorization(req, challenge, nc)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", auth)
+	return true, nil
+}
+
+// challenged reads digest challenge from a 401 response, and reports whether the request
+// should be sent again with the new challenge. Request that has been sent with credentials
+// is sent again only if server says the nonce is stale, otherwise the credentials are wrong.
+func (d *digestAuth) challenged(resp *http.Response, authorized bool) bool {
+	challenge := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if challenge == nil || (authorized && !challenge.stale) {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.challenge == nil || d.challenge.nonce != challenge.nonce {
+		d.nc = 0
+	}
+	d.challenge = challenge
+	return true
+}
+
+// authorization computes the `Authorization` header value, see RFC 7616 section 3.4.
+func (d *digestAuth) authorization(req *http.Request, challenge *digestChallenge, nc uint32) (string, error) {
+	h := digestHash(challenge.algorithm)
+	uri := req.URL.RequestURI()
+	ncValue := fmt.Sprintf("%08x", nc)
+	cnonce, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	ha1 := h(d.username + ":" + challenge.realm + ":" + d.password)
+	if challenge.sess {
+		ha1 = h(ha1 + ":" + challenge.nonce + ":" + cnonce)
+	}
+
+	qop := ""
+	for _, q := range challenge.qop {
+		if q == "auth" || (q == "auth-int" && qop == "") {
+			qop = q
+		}
+	}
+
+	ha2 := h(req.Method + ":" + uri)
+	if qop == "auth-int" {
+		body, err := requestBody(req)
+		if err != nil {
+			return "", err
+		}
+		ha2 = h(req.Method + ":" + uri + ":" + h(string(body)))
+	}
+
+	var response string
+	if qop == "" {
+		// RFC 2069 compatibility, server does not send qop
+		response = h(ha1 + ":" + challenge.nonce + ":" + ha2)
+	} else {
+		response = h(strings.Join([]string{ha1, challenge.nonce, ncValue, cnonce, qop, ha2}, ":"))
+	}
+
+	algorithm := challenge.algorithm
+	if challenge.sess {
+		algorithm += "-sess"
+	}
+	// username is hashed if server asks, and sent as extended value if it can not be a quoted string,
+	// see RFC 7616 section 3.4.4
+	var username string
+	switch {
+	case challenge.userhash:
+		username = "username=" + quoteAuthParam(h(d.username+":"+challenge.realm))
+	case !isQuotable(d.username):
+		username = "username*=" + encodeExtValue(d.username)
+	default:
+		username = "username=" + quoteAuthParam(d.username)
+	}
+	fields := []string{
+		username,
+		"realm=" + quoteAuthParam(challenge.realm),
+		"nonce=" + quoteAuthParam(challenge.nonce),
+		"uri=" + quoteAuthParam(uri),
+		"algorithm=" + algorithm,
+		"response=" + quoteAuthParam(response),
+	}
+	if challenge.opaque != "" {
+		fields = append(fields, "opaque="+quoteAuthParam(challenge.opaque))
+	}
+	if qop != "" {
+		fields = append(fields, "qop="+qop, "nc="+ncValue, "cnonce="+quoteAuthParam(cnonce))
+	}
+	if challenge.userhash {
+		fields = append(fields, "userhash=true")
+	}
+	return "Digest " + strings.Join(fields, ", "), nil
+}
+
+// quoteAuthParam returns value as quoted string of auth params, only `"` and `\` are escaped,
+// see RFC 7230 section 3.2.6.
+func quoteAuthParam(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		if value[i] == '"' || value[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(value[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// isQuotable reports whether value can be sent as quoted string, which only has visible ASCII
+// characters, spaces and tabs.
+func isQuotable(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; (c < 0x20 && c != '\t') || c >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeExtValue encodes value as UTF-8 extended value, like `UTF-8''J%C3%BCrgen`, see RFC 8187.
+func encodeExtValue(value string) string {
+	const attrChars = "!#$&+-.^_`|~"
+	var b strings.Builder
+	b.WriteString("UTF-8''")
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || strings.IndexByte(attrChars, c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// digestHash returns the function hashing data to lower case hex string with the algorithm.
+func digestHash(algorithm string) func(string) string {
+	var newHash func() hash.Hash
+	switch algorithm {
+	case "SHA-256":
+		newHash = sha256.New
+	case "SHA-512-256":
+		newHash = sha512.New512_256
+	default:
+		newHash = md5.New
+	}
+	return func(data string) string {
+		h := newHash()
+		h.Write([]byte(data))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+// parseDigestChallenge returns the digest challenge with the most preferred algorithm
+// from `WWW-Authenticate` header values, or nil if there is no supported one.
+func parseDigestChallenge(headers []string) *digestChallenge {
+	var best *digestChallenge
+	rank := func(c *digestChallenge) int {
+		for i, algorithm := range digestAlgorithms {
+			if algorithm == c.algorithm {
+				return len(digestAlgorithms) - i
+			}
+		}
+		return 0
+	}
+
+	for _, header := range headers {
+		scheme, rest := header, ""
+		if i := strings.IndexByte(header, ' '); i >= 0 {
+			scheme, rest = header[:i], header[i+1:]
+		}
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		params, err := parseAuthParams(rest)
+		if err != nil || params["nonce"] == "" {
+			continue
+		}
+
+		challenge := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: strings.ToUpper(params["algorithm"]),
+			stale:     strings.EqualFold(params["stale"], "true"),
+			userhash:  strings.EqualFold(params["userhash"], "true"),
+		}
+		if challenge.algorithm == "" {
+			challenge.algorithm = "MD5"
+		}
+		if strings.HasSuffix(challenge.algorithm, "-SESS") {
+			challenge.algorithm = strings.TrimSuffix(challenge.algorithm, "-SESS")
+			challenge.sess = true
+		}
+		for _, qop := range strings.Split(params["qop"], ",") {
+			if qop = strings.TrimSpace(qop); qop != "" {
+				challenge.qop = append(challenge.qop, qop)
+			}
+		}
+
+		if rank(challenge) > 0 && (best == nil || rank(challenge) > rank(best)) {
+			best = challenge
+		}
+	}
+	return best
+}
+
+// parseAuthParams parses comma separated `key=value` pairs of auth header,
+// values can be tokens or quoted strings, see RFC 7235 section 2.1.
+func parseAuthParams(s string) (map[string]string, error) {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params, nil
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("gohttp: invalid auth param %q", s)
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("gohttp: unterminated quoted string in auth params")
+			}
+			value, s = b.String(), s[i+1:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = strings.TrimSpace(s[:end]), s[end:]
+		}
+		params[key] = value
+	}
+}
@@ -0,0 +1,184 @@
+package gohttp_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// digestServer checks digest authorization of user `admin` with password `secret`.
+type digestServer struct {
+	*httptest.Server
+
+	algorithm string
+	qop       string
+
+	mu         sync.Mutex
+	nonce      string
+	challenges int
+	ncs        []string
+}
+
+var digestParam = regexp.MustCompile(`(\w+)=(?:"([^"]*)"|([^\s,]+))`)
+
+func newDigestServer(algorithm, qop string) *digestServer {
+	s := &digestServer{algorithm: algorithm, qop: qop, nonce: "nonce-1"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		params := map[string]string{}
+		for _, m := range digestParam.FindAllStringSubmatch(r.Header.Get("Authorization"), -1) {
+			params[m[1]] = m[2] + m[3]
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+
+		stale := params["nonce"] != "" && params["nonce"] != s.nonce
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Digest ") || stale ||
+			params["response"] != s.response(r.Method, params, body) {
+			s.challenges++
+			w.Header().Set("WWW-Authenticate", `Basic realm="old"`)
+			w.Header().Add("WWW-Authenticate", fmt.Sprintf(
+				`Digest realm="test@example.com", nonce=%q, opaque="op, aque", algorithm=%s, qop="%s", stale=%v`,
+				s.nonce, s.algorithm, s.qop, stale))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.ncs = append(s.ncs, params["nc"])
+		fmt.Fprintf(w, "hello %s", params["username"])
+	}))
+	return s
+}
+
+func (s *digestServer) response(method string, params map[string]string, body []byte) string {
+	h := func(data string) string {
+		var hh hash.Hash = md5.New()
+		if strings.HasPrefix(s.algorithm, "SHA-256") {
+			hh = sha256.New()
+		}
+		hh.Write([]byte(data))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+	ha1 := h("admin:test@example.com:secret")
+	if strings.HasSuffix(s.algorithm, "-sess") {
+		ha1 = h(ha1 + ":" + params["nonce"] + ":" + params["cnonce"])
+	}
+	ha2 := h(method + ":" + params["uri"])
+	if params["qop"] == "auth-int" {
+		ha2 = h(method + ":" + params["uri"] + ":" + h(string(body)))
+	}
+	return h(strings.Join([]string{ha1, params["nonce"], params["nc"], params["cnonce"], params["qop"], ha2}, ":"))
+}
+
+func TestDigestAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		algorithm string
+		qop       string
+	}{
+		{"MD5", "auth"},
+		{"MD5-sess", "auth"},
+		{"SHA-256", "auth,auth-int"},
+		{"SHA-256-sess", "auth-int"},
+	}
+	for _, tc := range cases {
+		ts := newDigestServer(tc.algorithm, tc.qop)
+
+		c := gohttp.New().URL(ts.URL).DigestAuth("admin", "secret")
+		resp, err := c.New().Path("/status").Query("verbose", "1").Get()
+		assert.NoError(err)
+		data, _ := resp.AsString()
+		assert.Equal("hello admin", data, tc.algorithm)
+
+		// challenge is reused by clones, body is hashed with auth-int
+		resp, err = c.New().JSON(`{"power":"off"}`).Post()
+		assert.NoError(err)
+		assert.Equal(http.StatusOK, resp.StatusCode, tc.algorithm)
+
+		assert.Equal(1, ts.challenges, tc.algorithm)
+		assert.Equal([]string{"00000001", "00000002"}, ts.ncs, tc.algorithm)
+		ts.Close()
+	}
+}
+
+func TestDigestAuthStaleNonce(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newDigestServer("MD5", "auth")
+	defer ts.Close()
+
+	c := gohttp.New().URL(ts.URL).DigestAuth("admin", "secret")
+	_, err := c.Get()
+	assert.NoError(err)
+
+	ts.mu.Lock()
+	ts.nonce = "nonce-2"
+	ts.mu.Unlock()
+
+	resp, err := c.Get()
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(2, ts.challenges)
+	assert.Equal([]string{"00000001", "00000001"}, ts.ncs, "nonce count restarts with new nonce")
+}
+
+func TestDigestAuthWrongPassword(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newDigestServer("MD5", "auth")
+	defer ts.Close()
+
+	resp, err := gohttp.New().DigestAuth("admin", "wrong").Get(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(2, ts.challenges, "request should be sent again only once")
+}
+
+// digestAuthorization sends a request with digest auth to a fake server answering `challenge`,
+// and returns the `Authorization` header of the authorized attempt.
+func digestAuthorization(t *testing.T, username, challenge string) string {
+	var authorization string
+	transport := gohttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+		if req.Header.Get("Authorization") == "" {
+			resp.StatusCode = http.StatusUnauthorized
+			resp.Header.Set("WWW-Authenticate", challenge)
+		}
+		authorization = req.Header.Get("Authorization")
+		return resp, nil
+	})
+	_, err := gohttp.New().Transport(transport).DigestAuth(username, "secret").Get("http://example.com/")
+	assert.NoError(t, err)
+	return authorization
+}
+
+func TestDigestAuthUsernameEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	header := digestAuthorization(t, `ad"m\in`, `Digest realm="r\"ealm", nonce="n", qop="auth"`)
+	assert.Contains(header, `username="ad\"m\\in"`)
+	assert.Contains(header, `realm="r\"ealm"`)
+
+	header = digestAuthorization(t, "Jürgen", `Digest realm="test", nonce="n", qop="auth"`)
+	assert.Contains(header, `username*=UTF-8''J%C3%BCrgen`)
+	assert.NotContains(header, `username=`)
+
+	sum := md5.Sum([]byte("Jürgen:test"))
+	header = digestAuthorization(t, "Jürgen", `Digest realm="test", nonce="n", qop="auth", userhash=true`)
+	assert.Contains(header, `username="`+hex.EncodeToString(sum[:])+`"`)
+	assert.Contains(header, "userhash=true")
+}
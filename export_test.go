@@ -0,0 +1,21 @@
+package gohttp
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+)
+
+// SetDeviceWait replaces how device authorization grant waits between polls,
+// and returns a function restoring the default.
+func SetDeviceWait(wait func(ctx context.Context, interval time.Duration) error) func() {
+	old := deviceWait
+	deviceWait = wait
+	return func() { deviceWait = old }
+}
+
+// SetLogOutput makes the client log to w.
+func SetLogOutput(c *Client, w io.Writer) {
+	c.logger = log.New(w, "", 0)
+}
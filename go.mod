@@ -0,0 +1,14 @@
+module github.com/cizixs/gohttp
+
+go 1.26
+
+require (
+	github.com/google/go-querystring v1.1.0
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -296,7 +296,7 @@ func TestGetWithQuery(t *testing.T) {
 	assert := assert.New(t)
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, r.URL.RawQuery)
+		fmt.Fprint(w, r.URL.RawQuery)
 	}))
 	defer ts.Close()
 
@@ -315,7 +315,7 @@ func TestGetWithQueryStruct(t *testing.T) {
 	assert := assert.New(t)
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, r.URL.RawQuery)
+		fmt.Fprint(w, r.URL.RawQuery)
 	}))
 	defer ts.Close()
 
@@ -469,7 +469,7 @@ func TestPostFiles(t *testing.T) {
 				}
 				files = append(files, fmt.Sprintf("%s:%d", part.FileName(), len(string(data))))
 			}
-			fmt.Fprintf(w, strings.Join(files, "&"))
+			fmt.Fprint(w, strings.Join(files, "&"))
 		}
 	}))
 
@@ -0,0 +1,73 @@
+package gohttp
+
+import "net/http"
+
+// BeforeRequestHook is called with the prepared request before it is sent.
+// It can modify the request, for example add authentication headers.
+// Returning an error aborts the request.
+type BeforeRequestHook func(req *http.Request) error
+
+// AfterResponseHook is called with the final response, after all retries are done.
+// Returning an error makes `Do` return the error together with the response.
+type AfterResponseHook func(resp *GoResponse) error
+
+// ErrorHook is called when request fails with an error, including errors
+// returned by other hooks. `req` is nil if the error happens before request is created.
+type ErrorHook func(req *http.Request, err error)
+
+// OnBeforeRequest registers a hook function that runs before each request is sent.
+// Hooks run in registration order, and are inherited by clients cloned with `New`.
+//
+// Usage:
+//    c := gohttp.New().OnBeforeRequest(func(req *http.Request) error {
+//        req.Header.Set("X-Request-ID", newRequestID())
+//        return nil
+//    })
+func (c *Client) OnBeforeRequest(hook BeforeRequestHook) *Client {
+	if hook != nil {
+		c.beforeRequestHooks = append(c.beforeRequestHooks, hook)
+	}
+	return c
+}
+
+// OnAfterResponse registers a hook function that runs after response is received.
+// Hooks run in registration order, and are inherited by clients cloned with `New`.
+func (c *Client) OnAfterResponse(hook AfterResponseHook) *Client {
+	if hook != nil {
+		c.afterResponseHooks = append(c.afterResponseHooks, hook)
+	}
+	return c
+}
+
+// OnError registers a hook function that runs when request fails.
+// Hooks run in registration order, and are inherited by clients cloned with `New`.
+func (c *Client) OnError(hook ErrorHook) *Client {
+	if hook != nil {
+		c.errorHooks = append(c.errorHooks, hook)
+	}
+	return c
+}
+
+func (c *Client) runBeforeRequestHooks(req *http.Request) error {
+	for _, hook := range c.beforeRequestHooks {
+		if err := hook(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) runAfterResponseHooks(resp *GoResponse) error {
+	for _, hook := range c.afterResponseHooks {
+		if err := hook(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) runErrorHooks(req *http.Request, err error) {
+	for _, hook := range c.errorHooks {
+		hook(req, err)
+	}
+}
@@ -0,0 +1,136 @@
+package gohttp_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+func TestHooksOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Token")))
+	}))
+	defer ts.Close()
+
+	calls := []string{}
+	c := gohttp.New().
+		OnBeforeRequest(func(req *http.Request) error {
+			calls = append(calls, "before1")
+			req.Header.Set("X-Token", "secret")
+			return nil
+		}).
+		OnBeforeRequest(func(req *http.Request) error {
+			calls = append(calls, "before2")
+			return nil
+		}).
+		OnAfterResponse(func(resp *gohttp.GoResponse) error {
+			calls = append(calls, "after")
+			return nil
+		})
+
+	resp, err := c.Get(ts.URL)
+	assert.NoError(err)
+	data, _ := resp.AsString()
+	assert.Equal("secret", data, "before hook should be able to modify request")
+	assert.Equal([]string{"before1", "before2", "after"}, calls)
+}
+
+func TestHooksInheritedByClone(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	count := 0
+	base := gohttp.New().URL(ts.URL).OnAfterResponse(func(resp *gohttp.GoResponse) error {
+		count++
+		return nil
+	})
+
+	cloneCount := 0
+	clone := base.New().OnAfterResponse(func(resp *gohttp.GoResponse) error {
+		cloneCount++
+		return nil
+	})
+
+	clone.Get()
+	base.New().Get()
+	assert.Equal(2, count, "clones should inherit hooks of base client")
+	assert.Equal(1, cloneCount, "hooks registered on clone should not leak to base client")
+}
+
+func TestBeforeRequestHookAborts(t *testing.T) {
+	assert := assert.New(t)
+
+	sent := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = true
+	}))
+	defer ts.Close()
+
+	hookErr := errors.New("not allowed")
+	var gotReq *http.Request
+	var gotErr error
+	_, err := gohttp.New().
+		OnBeforeRequest(func(req *http.Request) error {
+			return hookErr
+		}).
+		OnError(func(req *http.Request, err error) {
+			gotReq, gotErr = req, err
+		}).
+		Get(ts.URL)
+
+	assert.Equal(hookErr, err)
+	assert.False(sent, "request should not be sent")
+	assert.Equal(hookErr, gotErr)
+	assert.NotNil(gotReq)
+}
+
+func TestAfterResponseHookError(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	errorCount := 0
+	resp, err := gohttp.New().
+		OnAfterResponse(func(resp *gohttp.GoResponse) error {
+			if resp.StatusCode >= 400 {
+				return errors.New(resp.Status)
+			}
+			return nil
+		}).
+		OnError(func(req *http.Request, err error) {
+			errorCount++
+		}).
+		Get(ts.URL)
+
+	assert.EqualError(err, "403 Forbidden")
+	assert.Equal(http.StatusForbidden, resp.StatusCode, "response should be returned with hook error")
+	assert.Equal(1, errorCount)
+}
+
+func TestErrorHookOnTransportError(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	var gotErr error
+	_, err := gohttp.New().OnError(func(req *http.Request, err error) {
+		gotErr = err
+	}).Get(url)
+
+	assert.Error(err)
+	assert.Equal(err, gotErr)
+}
@@ -0,0 +1,740 @@
+package gohttp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSignatureLabel is the label of signature `MessageSigner` creates, if `Label` is empty.
+const DefaultSignatureLabel = "sig1"
+
+// MessageSigner signs requests and responses with HTTP Message Signatures, see RFC 9421.
+// It sets `Signature-Input` and `Signature` headers, and `Content-Digest` if it is covered.
+//
+// The algorithm is decided by the key:
+// - `ed25519.PrivateKey`: ed25519
+// - `*ecdsa.PrivateKey`: ecdsa-p256-sha256 or ecdsa-p384-sha384, depending on the curve
+// - `*rsa.PrivateKey`: rsa-pss-sha512, or rsa-v1_5-sha256 if `Algorithm` says so
+// - `[]byte`: hmac-sha256
+//
+// Usage:
+//    signer := &gohttp.MessageSigner{KeyID: "my-key", Key: privateKey}
+//    gohttp.New().Signer(signer).JSON(`{"hello":"world"}`).Post("https://api.example.com/foo")
+type MessageSigner struct {
+	// KeyID is sent as `keyid` parameter, so that the verifier knows which key to use
+	KeyID string
+
+	// Key is the private key, or shared secret of HMAC
+	Key interface{}
+
+	// Algorithm is sent as `alg` parameter if it is set, it must match the key
+	Algorithm string
+
+	// Label is the name of signature, empty means `sig1`
+	Label string
+
+	// Components are the covered components, in order. Derived components start with `@`,
+	// others are header names. Empty means `@method`, `@target-uri`, `@authority`, and
+	// `content-digest` if the request has body; or `@status` and `content-digest` for responses.
+	Components []string
+
+	// Tag is sent as `tag` parameter, it tells the application the signature is made for
+	Tag string
+
+	// Expires is how long the signature is valid, zero means no `expires` parameter
+	Expires time.Duration
+
+	// Nonce sends a random `nonce` parameter, so that verifier can detect replays
+	Nonce bool
+
+	// Now returns the signing time, nil means `time.Now`
+	Now func() time.Time
+}
+
+// MessageVerifier verifies HTTP Message Signatures, see RFC 9421.
+//
+// Usage:
+//    verifier := &gohttp.MessageVerifier{
+//        Keys:     map[string]interface{}{"server-key": publicKey},
+//        Required: []string{"@status", "content-digest"},
+//    }
+//    resp, err := gohttp.Get("https://api.example.com/foo")
+//    err = verifier.Verify(resp)
+type MessageVerifier struct {
+	// Keys maps `keyid` to public key, or shared secret of HMAC
+	Keys map[string]interface{}
+
+	// Algorithms maps `keyid` to the algorithm its key is used with. Keys not in it use the
+	// algorithm decided by key type, like `MessageSigner`: RSA keys need it for rsa-v1_5-sha256.
+	// The `alg` parameter of signature is never trusted, it is rejected if it does not match.
+	Algorithms map[string]string
+
+	// Label is the name of signature to verify, empty means the first one
+	Label string
+
+	// Required are the components that must be covered by signature
+	Required []string
+
+	// MaxAge rejects signatures created longer than it ago, zero means no limit
+	MaxAge time.Duration
+
+	// Now returns the verifying time, nil means `time.Now`
+	Now func() time.Time
+}
+
+// message is a request or response, which signature components are taken from.
+type message struct {
+	req  *http.Request
+	resp *http.Response
+}
+
+func (m message) header() http.Header {
+	if m.resp != nil {
+		return m.resp.Header
+	}
+	return m.req.Header
+}
+
+// body reads message body, and puts it back so that it can be read again.
+func (m message) body() ([]byte, error) {
+	if m.req != nil {
+		if m.req.GetBody == nil && m.req.Body != nil && m.req.Body != http.NoBody {
+			// incoming request on server side
+			data, err := ioutil.ReadAll(m.req.Body)
+			m.req.Body.Close()
+			m.req.Body = ioutil.NopCloser(bytes.NewReader(data))
+			return data, err
+		}
+		return requestBody(m.req)
+	}
+	if m.resp.Body == nil {
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(m.resp.Body)
+	m.resp.Body.Close()
+	m.resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return data, err
+}
+
+// component returns the value of a covered component, see RFC 9421 section 2.
+func (m message) component(name string) (string, error) {
+	if !strings.HasPrefix(name, "@") {
+		values := m.header().Values(name)
+		if len(values) == 0 {
+			return "", fmt.Errorf("gohttp: signature component %q not found in message", name)
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.TrimSpace(value)
+		}
+		return strings.Join(trimmed, ", "), nil
+	}
+
+	if name == "@status" {
+		if m.resp == nil {
+			return "", errors.New("gohttp: @status is only available in responses")
+		}
+		return strconv.Itoa(m.resp.StatusCode), nil
+	}
+	if m.req == nil {
+		return "", fmt.Errorf("gohttp: signature component %q is not supported in responses", name)
+	}
+
+	req := m.req
+	scheme := strings.ToLower(req.URL.Scheme)
+	if scheme == "" {
+		// incoming request on server side
+		scheme = "http"
+		if req.TLS != nil {
+			scheme = "https"
+		}
+	}
+	authority := strings.ToLower(requestHost(req))
+	switch name {
+	case "@method":
+		return req.Method, nil
+	case "@target-uri":
+		return scheme + "://" + authority + req.URL.RequestURI(), nil
+	case "@authority":
+		return authority, nil
+	case "@scheme":
+		return scheme, nil
+	case "@request-target":
+		return req.URL.RequestURI(), nil
+	case "@path":
+		if path := req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+	}
+	return "", fmt.Errorf("gohttp: signature component %q is not supported", name)
+}
+
+// signatureBase builds the signature base, see RFC 9421 section 2.5.
+func (m message) signatureBase(components []string, params string) (string, error) {
+	var b strings.Builder
+	for _, name := range components {
+		value, err := m.component(name)
+		if err != nil {
+			return "", err
+		}
+		quoted, err := serializeSFString(name)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(quoted + ": " + value + "\n")
+	}
+	b.WriteString(`"@signature-params": ` + params)
+	return b.String(), nil
+}
+
+// Sign signs the request, it implements `Signer` interface.
+func (s *MessageSigner) Sign(req *http.Request) error {
+	components := s.Components
+	if len(components) == 0 {
+		components = []string{"@method", "@target-uri", "@authority"}
+		if req.Body != nil && req.Body != http.NoBody {
+			components = append(components, "content-digest")
+		}
+	}
+	return s.sign(message{req: req}, components)
+}
+
+// SignResponse signs the response, it can be used by servers, or by middlewares.
+func (s *MessageSigner) SignResponse(resp *http.Response) error {
+	components := s.Components
+	if len(components) == 0 {
+		components = []string{"@status", "content-digest"}
+	}
+	return s.sign(message{resp: resp}, components)
+}
+
+func (s *MessageSigner) sign(m message, components []string) error {
+	alg, err := signatureAlgorithm(s.Key, s.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	header := m.header()
+	for _, name := range components {
+		if strings.EqualFold(name, "content-digest") && header.Get("Content-Digest") == "" {
+			body, err := m.body()
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256(body)
+			header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+		}
+	}
+
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	components = append([]string(nil), components...)
+	quoted := make([]string, len(components))
+	for i, name := range components {
+		components[i] = strings.ToLower(name)
+		if quoted[i], err = serializeSFString(components[i]); err != nil {
+			return err
+		}
+	}
+	params := "(" + strings.Join(quoted, " ") + ");created=" + strconv.FormatInt(now.Unix(), 10)
+	if s.Expires > 0 {
+		params += ";expires=" + strconv.FormatInt(now.Add(s.Expires).Unix(), 10)
+	}
+	var nonce string
+	if s.Nonce {
+		if nonce, err = randomString(16); err != nil {
+			return err
+		}
+	}
+	for _, param := range [][2]string{{"nonce", nonce}, {"alg", s.Algorithm}, {"keyid", s.KeyID}, {"tag", s.Tag}} {
+		if param[1] == "" {
+			continue
+		}
+		value, err := serializeSFString(param[1])
+		if err != nil {
+			return err
+		}
+		params += ";" + param[0] + "=" + value
+	}
+
+	base, err := m.signatureBase(components, params)
+	if err != nil {
+		return err
+	}
+	signature, err := signMessage(alg, s.Key, []byte(base))
+	if err != nil {
+		return err
+	}
+
+	label := s.Label
+	if label == "" {
+		label = DefaultSignatureLabel
+	}
+	header.Set("Signature-Input", label+"="+params)
+	header.Set("Signature", label+"=:"+base64.StdEncoding.EncodeToString(signature)+":")
+	return nil
+}
+
+// Verify verifies the signature of response.
+func (v *MessageVerifier) Verify(resp *GoResponse) error {
+	if resp == nil || resp.Response == nil {
+		return errors.New("gohttp: no response to verify")
+	}
+	return v.verify(message{resp: resp.Response})
+}
+
+// VerifyRequest verifies the signature of request, it can be used by servers.
+func (v *MessageVerifier) VerifyRequest(req *http.Request) error {
+	if req == nil {
+		return errors.New("gohttp: no request to verify")
+	}
+	return v.verify(message{req: req})
+}
+
+func (v *MessageVerifier) verify(m message) error {
+	header := m.header()
+	inputs, err := parseSignatureInput(strings.Join(header.Values("Signature-Input"), ", "))
+	if err != nil {
+		return err
+	}
+	signatures, err := parseSignatures(strings.Join(header.Values("Signature"), ", "))
+	if err != nil {
+		return err
+	}
+
+	var input *signatureInput
+	for _, in := range inputs {
+		if v.Label == "" || in.label == v.Label {
+			input = in
+			break
+		}
+	}
+	if input == nil {
+		return errors.New("gohttp: message signature not found")
+	}
+	signature, ok := signatures[input.label]
+	if !ok {
+		return fmt.Errorf("gohttp: signature %q not found", input.label)
+	}
+
+	for _, required := range v.Required {
+		found := false
+		for _, name := range input.components {
+			if name == strings.ToLower(required) {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("gohttp: required component %q is not signed", required)
+		}
+	}
+
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	if value, ok := input.params["expires"]; ok {
+		expires, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || now.Unix() > expires {
+			return errors.New("gohttp: message signature expired")
+		}
+	}
+	if v.MaxAge > 0 {
+		created, err := strconv.ParseInt(input.params["created"], 10, 64)
+		if err != nil || now.Sub(time.Unix(created, 0)) > v.MaxAge {
+			return errors.New("gohttp: message signature is too old")
+		}
+	}
+
+	key, ok := v.Keys[input.params["keyid"]]
+	if !ok {
+		return fmt.Errorf("gohttp: unknown signature key %q", input.params["keyid"])
+	}
+	// algorithm comes from verifier's configuration, never from the message
+	alg, err := signatureAlgorithm(key, v.Algorithms[input.params["keyid"]])
+	if err != nil {
+		return err
+	}
+	if signedAlg, ok := input.params["alg"]; ok && signedAlg != alg {
+		return fmt.Errorf("gohttp: signature algorithm %q is not allowed for key %q", signedAlg, input.params["keyid"])
+	}
+
+	for _, name := range input.components {
+		if name == "content-digest" {
+			if err := verifyContentDigest(m); err != nil {
+				return err
+			}
+		}
+	}
+
+	base, err := m.signatureBase(input.components, input.raw)
+	if err != nil {
+		return err
+	}
+	return verifyMessage(alg, key, []byte(base), signature)
+}
+
+// verifyContentDigest checks `Content-Digest` header matches the body, see RFC 9530.
+func verifyContentDigest(m message) error {
+	digests, err := parseSignatures(m.header().Get("Content-Digest"))
+	if err != nil {
+		return err
+	}
+	body, err := m.body()
+	if err != nil {
+		return err
+	}
+	for alg, digest := range digests {
+		var sum []byte
+		switch alg {
+		case "sha-256":
+			s := sha256.Sum256(body)
+			sum = s[:]
+		case "sha-512":
+			s := sha512.Sum512(body)
+			sum = s[:]
+		default:
+			continue
+		}
+		if !bytes.Equal(sum, digest) {
+			return errors.New("gohttp: content digest does not match body")
+		}
+		return nil
+	}
+	return errors.New("gohttp: no supported content digest algorithm")
+}
+
+// signatureAlgorithm returns the algorithm name of the key, if alg is set it must match the key.
+func signatureAlgorithm(key interface{}, alg string) (string, error) {
+	var algs []string
+	switch k := key.(type) {
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		algs = []string{"ed25519"}
+	case *ecdsa.PrivateKey:
+		algs = ecdsaAlgorithm(k.Curve)
+	case *ecdsa.PublicKey:
+		algs = ecdsaAlgorithm(k.Curve)
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		algs = []string{"rsa-pss-sha512", "rsa-v1_5-sha256"}
+	case []byte:
+		algs = []string{"hmac-sha256"}
+	}
+	if len(algs) == 0 {
+		return "", fmt.Errorf("gohttp: unsupported signature key type %T", key)
+	}
+	if alg == "" {
+		return algs[0], nil
+	}
+	for _, a := range algs {
+		if a == alg {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("gohttp: signature algorithm %q does not match key type %T", alg, key)
+}
+
+func ecdsaAlgorithm(curve elliptic.Curve) []string {
+	switch curve {
+	case elliptic.P256():
+		return []string{"ecdsa-p256-sha256"}
+	case elliptic.P384():
+		return []string{"ecdsa-p384-sha384"}
+	}
+	return nil
+}
+
+func signatureHash(alg string) (crypto.Hash, func() hash.Hash) {
+	switch alg {
+	case "rsa-pss-sha512":
+		return crypto.SHA512, sha512.New
+	case "ecdsa-p384-sha384":
+		return crypto.SHA384, sha512.New384
+	}
+	return crypto.SHA256, sha256.New
+}
+
+func signMessage(alg string, key interface{}, base []byte) ([]byte, error) {
+	hashID, newHash := signatureHash(alg)
+	h := newHash()
+	h.Write(base)
+	digest := h.Sum(nil)
+
+	switch alg {
+	case "ed25519":
+		k, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("gohttp: ed25519 signing needs a private key")
+		}
+		return ed25519.Sign(k, base), nil
+	case "ecdsa-p256-sha256", "ecdsa-p384-sha384":
+		k, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("gohttp: ecdsa signing needs a private key")
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			return nil, err
+		}
+		// signature is r and s concatenated, each padded to the curve size
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature, nil
+	case "rsa-pss-sha512", "rsa-v1_5-sha256":
+		k, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("gohttp: rsa signing needs a private key")
+		}
+		if alg == "rsa-v1_5-sha256" {
+			return rsa.SignPKCS1v15(rand.Reader, k, hashID, digest)
+		}
+		return rsa.SignPSS(rand.Reader, k, hashID, digest, &rsa.PSSOptions{SaltLength: 64})
+	case "hmac-sha256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write(base)
+		return mac.Sum(nil), nil
+	}
+	return nil, fmt.Errorf("gohttp: unsupported signature algorithm %q", alg)
+}
+
+func verifyMessage(alg string, key interface{}, base, signature []byte) error {
+	hashID, newHash := signatureHash(alg)
+	h := newHash()
+	h.Write(base)
+	digest := h.Sum(nil)
+
+	valid := false
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		valid = ed25519.Verify(k.Public().(ed25519.PublicKey), base, signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(k, base, signature)
+	case *ecdsa.PrivateKey:
+		valid = verifyECDSA(&k.PublicKey, digest, signature)
+	case *ecdsa.PublicKey:
+		valid = verifyECDSA(k, digest, signature)
+	case *rsa.PrivateKey:
+		valid = verifyRSA(alg, &k.PublicKey, hashID, digest, signature)
+	case *rsa.PublicKey:
+		valid = verifyRSA(alg, k, hashID, digest, signature)
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write(base)
+		valid = hmac.Equal(mac.Sum(nil), signature)
+	}
+	if !valid {
+		return errors.New("gohttp: message signature is invalid")
+	}
+	return nil
+}
+
+func verifyECDSA(key *ecdsa.PublicKey, digest, signature []byte) bool {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		return false
+	}
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	return ecdsa.Verify(key, digest, r, s)
+}
+
+func verifyRSA(alg string, key *rsa.PublicKey, hashID crypto.Hash, digest, signature []byte) bool {
+	if alg == "rsa-v1_5-sha256" {
+		return rsa.VerifyPKCS1v15(key, hashID, digest, signature) == nil
+	}
+	return rsa.VerifyPSS(key, hashID, digest, signature, &rsa.PSSOptions{SaltLength: 64}) == nil
+}
+
+// signatureInput is a member of `Signature-Input` header.
+type signatureInput struct {
+	label      string
+	components []string
+	params     map[string]string
+
+	// raw is the serialized value, it is used as is in signature base
+	raw string
+}
+
+// parseSignatureInput parses `Signature-Input` header, which is a structured field dictionary
+// of inner lists, see RFC 8941. Components with parameters are not supported.
+func parseSignatureInput(header string) ([]*signatureInput, error) {
+	members, err := splitDictionary(header)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]*signatureInput, 0, len(members))
+	for _, member := range members {
+		input := &signatureInput{label: member[0], raw: member[1], params: make(map[string]string)}
+		s := member[1]
+		if !strings.HasPrefix(s, "(") {
+			return nil, fmt.Errorf("gohttp: invalid signature input %q", s)
+		}
+		s = s[1:]
+		for {
+			s = strings.TrimLeft(s, " ")
+			if strings.HasPrefix(s, ")") {
+				s = s[1:]
+				break
+			}
+			value, rest, err := parseSFString(s)
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(rest, ";") {
+				return nil, fmt.Errorf("gohttp: signature component parameters are not supported: %q", member[1])
+			}
+			input.components = append(input.components, value)
+			s = rest
+		}
+
+		for strings.HasPrefix(s, ";") {
+			eq := strings.IndexByte(s, '=')
+			if eq < 0 {
+				return nil, fmt.Errorf("gohttp: invalid signature parameter %q", s)
+			}
+			key := s[1:eq]
+			s = s[eq+1:]
+			var value string
+			if strings.HasPrefix(s, `"`) {
+				value, s, err = parseSFString(s)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				end := strings.IndexByte(s, ';')
+				if end < 0 {
+					end = len(s)
+				}
+				value, s = s[:end], s[end:]
+			}
+			input.params[key] = value
+		}
+		if s != "" {
+			return nil, fmt.Errorf("gohttp: invalid signature input %q", member[1])
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, nil
+}
+
+// parseSignatures parses dictionary of byte sequences, like `Signature` and `Content-Digest` headers.
+func parseSignatures(header string) (map[string][]byte, error) {
+	members, err := splitDictionary(header)
+	if err != nil {
+		return nil, err
+	}
+	signatures := make(map[string][]byte, len(members))
+	for _, member := range members {
+		value := member[1]
+		if len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+			return nil, fmt.Errorf("gohttp: invalid byte sequence %q", value)
+		}
+		data, err := base64.StdEncoding.DecodeString(value[1 : len(value)-1])
+		if err != nil {
+			return nil, err
+		}
+		signatures[member[0]] = data
+	}
+	return signatures, nil
+}
+
+// splitDictionary splits structured field dictionary into key and raw value pairs, in order.
+func splitDictionary(s string) ([][2]string, error) {
+	var members [][2]string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return members, nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("gohttp: invalid dictionary member %q", s)
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = s[eq+1:]
+
+		// value ends at the first comma outside of quoted string
+		end, quoted := len(s), false
+		for i := 0; i < len(s); i++ {
+			if quoted && s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				quoted = !quoted
+			} else if s[i] == ',' && !quoted {
+				end = i
+				break
+			}
+		}
+		members = append(members, [2]string{key, strings.TrimSpace(s[:end])})
+		if end == len(s) {
+			return members, nil
+		}
+		s = s[end+1:]
+	}
+}
+
+// serializeSFString serializes s as a structured field string, see RFC 8941 section 4.1.6.
+// Only `"` and `\` are escaped, other characters must be printable ASCII.
+func serializeSFString(s string) (string, error) {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c > 0x7e {
+			return "", fmt.Errorf("gohttp: invalid character in structured field string %q", s)
+		}
+		if c == '"' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+	return b.String(), nil
+}
+
+// parseSFString parses a structured field string at the beginning of s, and returns the rest.
+func parseSFString(s string) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", fmt.Errorf("gohttp: expect string at %q", s)
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:], nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", errors.New("gohttp: unterminated string")
+}
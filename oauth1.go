@@ -0,0 +1,296 @@
+package gohttp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OAuth1 signature methods, see RFC 5849 section 3.4.
+const (
+	OAuth1HMACSHA1   = "HMAC-SHA1"
+	OAuth1HMACSHA256 = "HMAC-SHA256"
+	OAuth1PlainText  = "PLAINTEXT"
+)
+
+// OAuth1Config describes an OAuth 1.0a client, see RFC 5849. It creates signers for
+// `Client.Signer`, and gets token credentials with the three-legged flow.
+//
+// Usage:
+//    cfg := &gohttp.OAuth1Config{
+//        ConsumerKey:     "key",
+//        ConsumerSecret:  "secret",
+//        RequestTokenURL: "https://api.example.com/oauth/request_token",
+//        AuthorizeURL:    "https://api.example.com/oauth/authorize",
+//        AccessTokenURL:  "https://api.example.com/oauth/access_token",
+//    }
+//    temp, err := cfg.RequestToken()
+//    fmt.Println("Authorize the application at:", cfg.AuthorizationURL(temp))
+//    token, err := cfg.AccessToken(temp, verifier)
+//    c := gohttp.New().Signer(cfg.Signer(token))
+type OAuth1Config struct {
+	// ConsumerKey and ConsumerSecret are the client credentials
+	ConsumerKey    string
+	ConsumerSecret string
+
+	// RequestTokenURL is the temporary credential request endpoint
+	RequestTokenURL string
+
+	// AuthorizeURL is the resource owner authorization endpoint
+	AuthorizeURL string
+
+	// AccessTokenURL is the token request endpoint
+	AccessTokenURL string
+
+	// Callback is where user is redirected to after authorization, empty means `oob`,
+	// which means out-of-band, user copies the verifier to the application.
+	Callback string
+
+	// SignatureMethod is one of `HMAC-SHA1`, `HMAC-SHA256` and `PLAINTEXT`, empty means `HMAC-SHA1`
+	SignatureMethod string
+
+	// Client is the base client used to send credential requests, nil means `New()`.
+	Client *Client
+}
+
+// OAuth1Credentials are the token and secret pair, either temporary credentials or token credentials.
+type OAuth1Credentials struct {
+	Token  string
+	Secret string
+}
+
+// OAuth1Signer signs requests with OAuth 1.0a, it implements `Signer` interface.
+//
+// Signature covers query parameters, parameters in `application/x-www-form-urlencoded`
+// body, and protocol parameters, they are collected from the request as it is sent.
+type OAuth1Signer struct {
+	// ConsumerKey and ConsumerSecret are the client credentials
+	ConsumerKey    string
+	ConsumerSecret string
+
+	// Token and TokenSecret are the token credentials, or temporary credentials
+	Token       string
+	TokenSecret string
+
+	// SignatureMethod is one of `HMAC-SHA1`, `HMAC-SHA256` and `PLAINTEXT`, empty means `HMAC-SHA1`
+	SignatureMethod string
+
+	// Realm is sent in `Authorization` header if it is set, it is not signed
+	Realm string
+
+	// Callback and Verifier are only used in the three-legged flow
+	Callback string
+	Verifier string
+
+	// Now returns the signing time, nil means `time.Now`
+	Now func() time.Time
+
+	// Nonce returns a random string, nil means a 16-byte random string
+	Nonce func() string
+}
+
+// Signer returns a signer of requests with token credentials.
+func (cfg *OAuth1Config) Signer(token *OAuth1Credentials) *OAuth1Signer {
+	return &OAuth1Signer{
+		ConsumerKey:     cfg.ConsumerKey,
+		ConsumerSecret:  cfg.ConsumerSecret,
+		Token:           token.Token,
+		TokenSecret:     token.Secret,
+		SignatureMethod: cfg.SignatureMethod,
+	}
+}
+
+// RequestToken gets temporary credentials, see RFC 5849 section 2.1.
+func (cfg *OAuth1Config) RequestToken() (*OAuth1Credentials, error) {
+	callback := cfg.Callback
+	if callback == "" {
+		callback = "oob"
+	}
+	signer := cfg.Signer(&OAuth1Credentials{})
+	signer.Callback = callback
+
+	values, err := cfg.requestCredentials(cfg.RequestTokenURL, signer)
+	if err != nil {
+		return nil, err
+	}
+	if values.Get("oauth_callback_confirmed") != "true" {
+		return nil, errors.New("oauth1: server does not confirm callback")
+	}
+	return &OAuth1Credentials{Token: values.Get("oauth_token"), Secret: values.Get("oauth_token_secret")}, nil
+}
+
+// AuthorizationURL returns the url where user authorizes the temporary credentials, see RFC 5849 section 2.2.
+func (cfg *OAuth1Config) AuthorizationURL(temp *OAuth1Credentials) string {
+	u, err := url.Parse(cfg.AuthorizeURL)
+	if err != nil {
+		return cfg.AuthorizeURL
+	}
+	q := u.Query()
+	q.Set("oauth_token", temp.Token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// AccessToken exchanges authorized temporary credentials and the verifier for token credentials,
+// see RFC 5849 section 2.3.
+func (cfg *OAuth1Config) AccessToken(temp *OAuth1Credentials, verifier string) (*OAuth1Credentials, error) {
+	signer := cfg.Signer(temp)
+	signer.Verifier = verifier
+
+	values, err := cfg.requestCredentials(cfg.AccessTokenURL, signer)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuth1Credentials{Token: values.Get("oauth_token"), Secret: values.Get("oauth_token_secret")}, nil
+}
+
+// requestCredentials sends a signed POST request to endpoint, and parses credentials in form-encoded response.
+func (cfg *OAuth1Config) requestCredentials(endpoint string, signer *OAuth1Signer) (url.Values, error) {
+	c := New()
+	if cfg.Client != nil {
+		c = cfg.Client.New()
+	}
+	resp, err := c.Signer(signer).Post(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := resp.AsString()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oauth1: credential request failed with status %d: %s", resp.StatusCode, data)
+	}
+	values, err := url.ParseQuery(data)
+	if err != nil {
+		return nil, fmt.Errorf("oauth1: can not parse credential response: %v", err)
+	}
+	if values.Get("oauth_token") == "" || values.Get("oauth_token_secret") == "" {
+		return nil, errors.New("oauth1: server response missing oauth_token or oauth_token_secret")
+	}
+	return values, nil
+}
+
+// Sign sets `Authorization: OAuth ...` header of the request.
+func (s *OAuth1Signer) Sign(req *http.Request) error {
+	method := s.SignatureMethod
+	if method == "" {
+		method = OAuth1HMACSHA1
+	}
+
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	var nonce string
+	if s.Nonce != nil {
+		nonce = s.Nonce()
+	} else {
+		var err error
+		if nonce, err = randomString(16); err != nil {
+			return err
+		}
+	}
+
+	oauthParams := map[string]string{
+		"oauth_consumer_key":     s.ConsumerKey,
+		"oauth_nonce":            nonce,
+		"oauth_signature_method": method,
+		"oauth_timestamp":        strconv.FormatInt(now.Unix(), 10),
+		"oauth_version":          "1.0",
+	}
+	if s.Token != "" {
+		oauthParams["oauth_token"] = s.Token
+	}
+	if s.Callback != "" {
+		oauthParams["oauth_callback"] = s.Callback
+	}
+	if s.Verifier != "" {
+		oauthParams["oauth_verifier"] = s.Verifier
+	}
+
+	key := escapeRFC3986(s.ConsumerSecret) + "&" + escapeRFC3986(s.TokenSecret)
+	var signature string
+	switch method {
+	case OAuth1PlainText:
+		signature = key
+	case OAuth1HMACSHA1, OAuth1HMACSHA256:
+		base, err := oauth1BaseString(req, oauthParams)
+		if err != nil {
+			return err
+		}
+		var newHash func() hash.Hash = sha1.New
+		if method == OAuth1HMACSHA256 {
+			newHash = sha256.New
+		}
+		mac := hmac.New(newHash, []byte(key))
+		mac.Write([]byte(base))
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	default:
+		return fmt.Errorf("oauth1: unsupported signature method %q", method)
+	}
+	oauthParams["oauth_signature"] = signature
+
+	keys := make([]string, 0, len(oauthParams))
+	for k := range oauthParams {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(keys)+1)
+	if s.Realm != "" {
+		fields = append(fields, fmt.Sprintf(`realm="%s"`, escapeRFC3986(s.Realm)))
+	}
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf(`%s="%s"`, k, escapeRFC3986(oauthParams[k])))
+	}
+	req.Header.Set("Authorization", "OAuth "+strings.Join(fields, ", "))
+	return nil
+}
+
+// oauth1BaseString builds the signature base string, see RFC 5849 section 3.4.1.
+func oauth1BaseString(req *http.Request, oauthParams map[string]string) (string, error) {
+	params := req.URL.Query()
+	if params == nil {
+		params = url.Values{}
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), formContentType) {
+		body, err := requestBody(req)
+		if err != nil {
+			return "", err
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", err
+		}
+		for k, vs := range form {
+			params[k] = append(params[k], vs...)
+		}
+	}
+	for k, v := range oauthParams {
+		params.Add(k, v)
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	baseURI := strings.ToLower(req.URL.Scheme) + "://" + strings.ToLower(requestHost(req)) + path
+	return strings.Join([]string{
+		strings.ToUpper(req.Method),
+		escapeRFC3986(baseURI),
+		escapeRFC3986(canonicalQuery(params)),
+	}, "&"), nil
+}
@@ -0,0 +1,150 @@
+package gohttp_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// TestOAuth1Signer uses the example of OAuth Core 1.0 appendix A.
+func TestOAuth1Signer(t *testing.T) {
+	assert := assert.New(t)
+
+	signer := &gohttp.OAuth1Signer{
+		ConsumerKey:    "dpf43f3p2l4k3l03",
+		ConsumerSecret: "kd94hf93k423kf44",
+		Token:          "nnch734d00sl2jdk",
+		TokenSecret:    "pfkkdhi9sl3r4s00",
+		Realm:          "http://photos.example.net/",
+		Now:            func() time.Time { return time.Unix(1191242096, 0) },
+		Nonce:          func() string { return "kllo9940pd9333jh" },
+	}
+	req, _ := http.NewRequest("GET", "http://photos.example.net/photos?file=vacation.jpg&size=original", nil)
+	assert.NoError(signer.Sign(req))
+	assert.Equal(`OAuth realm="http%3A%2F%2Fphotos.example.net%2F", oauth_consumer_key="dpf43f3p2l4k3l03", `+
+		`oauth_nonce="kllo9940pd9333jh", oauth_signature="tR3%2BTy81lMeYAr%2FFid0kMTYa%2FWM%3D", `+
+		`oauth_signature_method="HMAC-SHA1", oauth_timestamp="1191242096", oauth_token="nnch734d00sl2jdk", `+
+		`oauth_version="1.0"`, req.Header.Get("Authorization"))
+}
+
+var oauthParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// verifyOAuth1 checks HMAC-SHA1 signature of the request independently, and returns the protocol parameters.
+func verifyOAuth1(r *http.Request, consumerSecret string, tokenSecrets map[string]string) (map[string]string, bool) {
+	escape := func(s string) string {
+		return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+	}
+
+	oauth := map[string]string{}
+	params := url.Values{}
+	for _, m := range oauthParam.FindAllStringSubmatch(r.Header.Get("Authorization"), -1) {
+		value, _ := url.QueryUnescape(m[2])
+		oauth[m[1]] = value
+		if m[1] != "oauth_signature" && m[1] != "realm" {
+			params.Add(m[1], value)
+		}
+	}
+	r.ParseForm()
+	for k, vs := range r.Form {
+		params[k] = append(params[k], vs...)
+	}
+
+	pairs := []string{}
+	for k, vs := range params {
+		for _, v := range vs {
+			pairs = append(pairs, escape(k)+"="+escape(v))
+		}
+	}
+	sort.Strings(pairs)
+	base := r.Method + "&" + escape("http://"+r.Host+r.URL.Path) + "&" + escape(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, []byte(escape(consumerSecret)+"&"+escape(tokenSecrets[oauth["oauth_token"]])))
+	mac.Write([]byte(base))
+	return oauth, base64.StdEncoding.EncodeToString(mac.Sum(nil)) == oauth["oauth_signature"]
+}
+
+func TestOAuth1ThreeLegged(t *testing.T) {
+	assert := assert.New(t)
+
+	tokenSecrets := map[string]string{"": "", "temp": "temp secret", "final": "final/secret"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/request_token", func(w http.ResponseWriter, r *http.Request) {
+		oauth, ok := verifyOAuth1(r, "consumer secret", tokenSecrets)
+		if !ok || oauth["oauth_callback"] != "oob" {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "oauth_token=temp&oauth_token_secret=temp+secret&oauth_callback_confirmed=true")
+	})
+	mux.HandleFunc("/access_token", func(w http.ResponseWriter, r *http.Request) {
+		oauth, ok := verifyOAuth1(r, "consumer secret", tokenSecrets)
+		if !ok || oauth["oauth_token"] != "temp" || oauth["oauth_verifier"] != "verifier-1" {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "oauth_token=final&oauth_token_secret=final%2Fsecret")
+	})
+	mux.HandleFunc("/statuses", func(w http.ResponseWriter, r *http.Request) {
+		oauth, ok := verifyOAuth1(r, "consumer secret", tokenSecrets)
+		if !ok || oauth["oauth_token"] != "final" {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if r.PostForm.Get("status") != "Hello, world! ~*" {
+			http.Error(w, "missing status", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	cfg := &gohttp.OAuth1Config{
+		ConsumerKey:     "consumer",
+		ConsumerSecret:  "consumer secret",
+		RequestTokenURL: ts.URL + "/request_token",
+		AuthorizeURL:    ts.URL + "/authorize?lang=en",
+		AccessTokenURL:  ts.URL + "/access_token",
+	}
+	temp, err := cfg.RequestToken()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(&gohttp.OAuth1Credentials{Token: "temp", Secret: "temp secret"}, temp)
+	assert.Equal(ts.URL+"/authorize?lang=en&oauth_token=temp", cfg.AuthorizationURL(temp))
+
+	token, err := cfg.AccessToken(temp, "verifier-1")
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("final/secret", token.Secret)
+
+	// query and form parameters are signed as they are sent
+	type filter struct {
+		Since string `url:"since"`
+	}
+	type update struct {
+		Status string `url:"status"`
+	}
+	c := gohttp.New().URL(ts.URL).Signer(cfg.Signer(token))
+	resp, err := c.New().Path("/statuses").Query("tag", "a b").QueryStruct(&filter{Since: "2024-01-01"}).
+		Form(&update{Status: "Hello, world! ~*"}).Post()
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	_, err = cfg.AccessToken(temp, "wrong")
+	assert.Error(err)
+}
@@ -0,0 +1,250 @@
+package gohttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2Config describes an OAuth2 client registered on authorization server, and
+// where to get tokens from. Tokens it returns can be used by `Client.TokenSource`.
+//
+// Usage:
+//    cfg := &gohttp.OAuth2Config{
+//        ClientID:     "my-service",
+//        ClientSecret: "secret",
+//        TokenURL:     "https://auth.example.com/oauth/token",
+//    }
+//    c := gohttp.New().TokenSource(cfg.ClientCredentials("orders:read"))
+type OAuth2Config struct {
+	// ClientID is the application's ID
+	ClientID string
+
+	// ClientSecret is the application's secret, it can be empty for public clients
+	ClientSecret string
+
+	// TokenURL is the token endpoint of authorization server
+	TokenURL string
+
+	// AuthURL is the authorization endpoint, it is used by authorization code flow
+	AuthURL string
+
+	// RedirectURL is where authorization server redirects to after user authorizes the client,
+	// it is used by authorization code flow
+	RedirectURL string
+
+	// DeviceAuthURL is the device authorization endpoint, it is used by device authorization grant
+	DeviceAuthURL string
+
+	// Scopes are the default scopes requested if none is given
+	Scopes []string
+
+	// AuthInParams sends client credentials in request body instead of basic auth header,
+	// for servers that do not support the latter.
+	AuthInParams bool
+
+	// Client is the base client used to send token requests, nil means `New()`.
+	// It is useful to set timeout or proxy for token requests.
+	Client *Client
+
+	// client credentials token sources, one for each scope set
+	mu      sync.Mutex
+	sources map[string]*cachedTokenSource
+}
+
+// OAuth2Error is the error response returned by authorization server, see RFC 6749 section 5.2.
+type OAuth2Error struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Code is the error code, like `invalid_client`, `invalid_grant`
+	Code string
+
+	// Description is the human-readable description of the error, if any
+	Description string
+
+	// URI is a link to the page describing the error, if any
+	URI string
+}
+
+func (e *OAuth2Error) Error() string {
+	msg := fmt.Sprintf("oauth2: %s (status %d)", e.Code, e.StatusCode)
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+// tokenRequest is the form data sent to token endpoint.
+type tokenRequest struct {
+	GrantType    string `url:"grant_type,omitempty"`
+	Scope        string `url:"scope,omitempty"`
+	RefreshToken string `url:"refresh_token,omitempty"`
+	Code         string `url:"code,omitempty"`
+	RedirectURI  string `url:"redirect_uri,omitempty"`
+	CodeVerifier string `url:"code_verifier,omitempty"`
+	DeviceCode   string `url:"device_code,omitempty"`
+	ClientID     string `url:"client_id,omitempty"`
+	ClientSecret string `url:"client_secret,omitempty"`
+}
+
+// tokenResponse is the response of token endpoint, both success and error response.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	ErrorURI         string `json:"error_uri"`
+}
+
+// scopeKey joins scopes into a canonical string, so that scope sets in different order are the same.
+func scopeKey(scopes []string) string {
+	sorted := make([]string, 0, len(scopes))
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		if scope != "" && !seen[scope] {
+			seen[scope] = true
+			sorted = append(sorted, scope)
+		}
+	}
+	sort.Strings(sorted)
+	return strings.Join(sorted, " ")
+}
+
+// ClientCredentials returns a token source which gets tokens with client credentials grant,
+// see RFC 6749 section 4.4. Default scopes are used if none is given.
+//
+// Tokens are cached per scope set: calling it with the same scopes, in any order,
+// returns the same token source, so tokens are shared by all clients using it.
+func (cfg *OAuth2Config) ClientCredentials(scopes ...string) TokenSource {
+	if len(scopes) == 0 {
+		scopes = cfg.Scopes
+	}
+	key := scopeKey(scopes)
+
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	if cfg.sources == nil {
+		cfg.sources = make(map[string]*cachedTokenSource)
+	}
+	if src, ok := cfg.sources[key]; ok {
+		return src
+	}
+	src := newCachedTokenSource(TokenSourceFunc(func() (*Token, error) {
+		return cfg.retrieveToken(context.Background(), &tokenRequest{GrantType: "client_credentials", Scope: key})
+	}))
+	cfg.sources[key] = src
+	return src
+}
+
+// TokenSource returns a token source which uses `token` until it expires, then gets
+// new tokens with refresh token grant, see RFC 6749 section 6.
+// If server issues a new refresh token, it replaces the old one.
+func (cfg *OAuth2Config) TokenSource(token *Token) TokenSource {
+	return newCachedTokenSource(&refreshTokenSource{cfg: cfg, token: token})
+}
+
+// refreshTokenSource returns the initial token if it is valid, and refreshes it afterwards.
+// Caching is done by `cachedTokenSource`, so every call other than the first one refreshes token.
+type refreshTokenSource struct {
+	cfg *OAuth2Config
+
+	mu    sync.Mutex
+	token *Token
+	used  bool
+}
+
+func (s *refreshTokenSource) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.used && s.token.Valid() {
+		s.used = true
+		return s.token, nil
+	}
+	s.used = true
+	if s.token == nil || s.token.RefreshToken == "" {
+		return nil, errors.New("oauth2: token expired and no refresh token is available")
+	}
+
+	token, err := s.cfg.retrieveToken(context.Background(), &tokenRequest{
+		GrantType:    "refresh_token",
+		RefreshToken: s.token.RefreshToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = s.token.RefreshToken
+	}
+	s.token = token
+	return token, nil
+}
+
+// tokenClient returns a client to send token requests, with client authentication configured.
+func (cfg *OAuth2Config) tokenClient(form *tokenRequest) *Client {
+	c := New()
+	if cfg.Client != nil {
+		c = cfg.Client.New()
+	}
+
+	if cfg.AuthInParams || cfg.ClientSecret == "" {
+		form.ClientID = cfg.ClientID
+		form.ClientSecret = cfg.ClientSecret
+	} else {
+		// client credentials are form-encoded before used in basic auth, see RFC 6749 section 2.3.1
+		c.BasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+	return c.Header("Accept", jsonContentType)
+}
+
+// retrieveToken sends form to token endpoint and parses the token in response, the request is bound to ctx.
+func (cfg *OAuth2Config) retrieveToken(ctx context.Context, form *tokenRequest) (*Token, error) {
+	resp, err := cfg.tokenClient(form).Form(form).DoContext(ctx, "POST", cfg.TokenURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return parseTokenResponse(resp)
+}
+
+func parseTokenResponse(resp *GoResponse) (*Token, error) {
+	data := tokenResponse{}
+	err := resp.AsJSON(&data)
+	if resp.StatusCode != http.StatusOK || data.Error != "" {
+		if data.Error == "" {
+			data.Error = http.StatusText(resp.StatusCode)
+		}
+		return nil, &OAuth2Error{
+			StatusCode:  resp.StatusCode,
+			Code:        data.Error,
+			Description: data.ErrorDescription,
+			URI:         data.ErrorURI,
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: can not parse token response: %v", err)
+	}
+	if data.AccessToken == "" {
+		return nil, errors.New("oauth2: server response missing access_token")
+	}
+
+	token := &Token{
+		AccessToken:  data.AccessToken,
+		TokenType:    data.TokenType,
+		RefreshToken: data.RefreshToken,
+	}
+	if data.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(data.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
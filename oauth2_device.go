@@ -0,0 +1,192 @@
+package gohttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// deviceGrantType is the grant type of device access token request, see RFC 8628 section 3.4.
+const deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// defaultDeviceInterval is the polling interval if server does not specify a positive one.
+const defaultDeviceInterval = 5 * time.Second
+
+// slowDownIncrement is how much polling interval grows when server asks to slow down, see RFC 8628 section 3.5.
+const slowDownIncrement = 5 * time.Second
+
+// deviceWait waits for the polling interval, or until ctx is done. Tests replace it to run fast.
+var deviceWait = func(ctx context.Context, interval time.Duration) error {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DeviceCode is the response of device authorization request, user should visit
+// `VerificationURI` and enter `UserCode` on another device to authorize the client.
+type DeviceCode struct {
+	// DeviceCode is the code the client polls token endpoint with
+	DeviceCode string
+
+	// UserCode is the code user enters on verification page
+	UserCode string
+
+	// VerificationURI is the page user visits to authorize the client
+	VerificationURI string
+
+	// VerificationURIComplete is the verification page with user code included, if server provides it.
+	// It is convenient to be shown as QR code.
+	VerificationURIComplete string
+
+	// Expiry is when the device code expires, zero value means unknown
+	Expiry time.Time
+
+	// Interval is the minimum waiting time between polling requests
+	Interval time.Duration
+}
+
+// deviceCodeResponse is the JSON response of device authorization endpoint.
+type deviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURL         string `json:"verification_url"` // used by some providers, like Google
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                *int64 `json:"interval"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	ErrorURI         string `json:"error_uri"`
+}
+
+// AuthorizeDevice runs OAuth2 device authorization grant for devices that can not open a browser,
+// see RFC 8628, and returns a token source for `Client.TokenSource`. The token is refreshed
+// automatically if server issues a refresh token.
+//
+// `prompt` is called with the device code, it should show user where to go and what code to enter.
+// Then token endpoint is polled until user authorizes the client, denies it, the device code
+// expires, or `ctx` is done, which also aborts the request in flight.
+//
+// Usage:
+//    src, err := cfg.AuthorizeDevice(ctx, func(code *gohttp.DeviceCode) error {
+//        fmt.Printf("Visit %s and enter code %s\n", code.VerificationURI, code.UserCode)
+//        return nil
+//    })
+//    c := gohttp.New().TokenSource(src)
+func (cfg *OAuth2Config) AuthorizeDevice(ctx context.Context, prompt func(*DeviceCode) error, scopes ...string) (TokenSource, error) {
+	token, err := cfg.DeviceToken(ctx, prompt, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.TokenSource(token), nil
+}
+
+// DeviceToken works the same way as `AuthorizeDevice`, except it returns the token,
+// so that it can be stored for later use.
+func (cfg *OAuth2Config) DeviceToken(ctx context.Context, prompt func(*DeviceCode) error, scopes ...string) (*Token, error) {
+	code, err := cfg.requestDeviceCode(ctx, scopes)
+	if err != nil {
+		return nil, err
+	}
+	if err := prompt(code); err != nil {
+		return nil, err
+	}
+
+	interval := code.Interval
+	for {
+		if !code.Expiry.IsZero() && time.Now().Add(interval).After(code.Expiry) {
+			return nil, &OAuth2Error{Code: "expired_token", Description: "device code expires before user authorizes"}
+		}
+
+		if err := deviceWait(ctx, interval); err != nil {
+			return nil, err
+		}
+
+		token, err := cfg.retrieveToken(ctx, &tokenRequest{GrantType: deviceGrantType, DeviceCode: code.DeviceCode})
+		if err == nil {
+			return token, nil
+		}
+		oauthErr, ok := err.(*OAuth2Error)
+		if !ok {
+			return nil, err
+		}
+		switch oauthErr.Code {
+		case "authorization_pending":
+			// user has not finished yet, keep polling
+		case "slow_down":
+			interval += slowDownIncrement
+		default:
+			// access_denied, expired_token, or other errors
+			return nil, err
+		}
+	}
+}
+
+// requestDeviceCode sends device authorization request, see RFC 8628 section 3.1.
+func (cfg *OAuth2Config) requestDeviceCode(ctx context.Context, scopes []string) (*DeviceCode, error) {
+	if cfg.DeviceAuthURL == "" {
+		return nil, errors.New("oauth2: device authorization url is not set")
+	}
+	if len(scopes) == 0 {
+		scopes = cfg.Scopes
+	}
+
+	form := &tokenRequest{Scope: scopeKey(scopes)}
+	c := cfg.tokenClient(form)
+	if form.ClientID == "" {
+		// client id is always needed in device authorization request
+		form.ClientID = cfg.ClientID
+	}
+	resp, err := c.Form(form).DoContext(ctx, "POST", cfg.DeviceAuthURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data := deviceCodeResponse{}
+	err = resp.AsJSON(&data)
+	if resp.StatusCode != http.StatusOK || data.Error != "" {
+		if data.Error == "" {
+			data.Error = http.StatusText(resp.StatusCode)
+		}
+		return nil, &OAuth2Error{
+			StatusCode:  resp.StatusCode,
+			Code:        data.Error,
+			Description: data.ErrorDescription,
+			URI:         data.ErrorURI,
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: can not parse device authorization response: %v", err)
+	}
+	if data.DeviceCode == "" || data.UserCode == "" {
+		return nil, errors.New("oauth2: server response missing device_code or user_code")
+	}
+
+	code := &DeviceCode{
+		DeviceCode:              data.DeviceCode,
+		UserCode:                data.UserCode,
+		VerificationURI:         data.VerificationURI,
+		VerificationURIComplete: data.VerificationURIComplete,
+		Interval:                defaultDeviceInterval,
+	}
+	if code.VerificationURI == "" {
+		code.VerificationURI = data.VerificationURL
+	}
+	if data.ExpiresIn > 0 {
+		code.Expiry = time.Now().Add(time.Duration(data.ExpiresIn) * time.Second)
+	}
+	// zero interval would make client poll without pause
+	if data.Interval != nil && *data.Interval > 0 {
+		code.Interval = time.Duration(*data.Interval) * time.Second
+	}
+	return code, nil
+}
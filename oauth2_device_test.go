@@ -0,0 +1,221 @@
+package gohttp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// fastDeviceWait makes device flow wait a thousandth of polling intervals, and records the intervals.
+func fastDeviceWait(intervals *[]time.Duration) func() {
+	return gohttp.SetDeviceWait(func(ctx context.Context, interval time.Duration) error {
+		*intervals = append(*intervals, interval)
+		select {
+		case <-time.After(interval / 1000):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// newDeviceServer returns an authorization server supporting device authorization grant,
+// token endpoint answers `authorization_pending` until it is polled `pending` times, then `final`.
+// `pollErrors` replace `authorization_pending` in the first answers.
+func newDeviceServer(pending int, final string, pollErrors ...string) (*httptest.Server, *int) {
+	var mu sync.Mutex
+	polled := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("client_id") != "my-tv" || r.PostForm.Get("scope") != "profile" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_request"}`)
+			return
+		}
+		fmt.Fprint(w, `{"device_code":"dev-1","user_code":"WDJB-MJHT","verification_uri":"https://example.com/device","expires_in":600,"interval":0}`)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" ||
+			r.PostForm.Get("device_code") != "dev-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+
+		mu.Lock()
+		polled++
+		n := polled
+		mu.Unlock()
+		switch {
+		case n <= pending:
+			code := "authorization_pending"
+			if n <= len(pollErrors) {
+				code = pollErrors[n-1]
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":%q}`, code)
+		case final == "":
+			fmt.Fprint(w, `{"access_token":"device-token","token_type":"Bearer","expires_in":3600}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":%q}`, final)
+		}
+	})
+	return httptest.NewServer(mux), &polled
+}
+
+func TestAuthorizeDevice(t *testing.T) {
+	assert := assert.New(t)
+
+	var intervals []time.Duration
+	defer fastDeviceWait(&intervals)()
+	auth, polled := newDeviceServer(2, "")
+	defer auth.Close()
+	api := newAPIServer(func(auth string) bool { return auth == "Bearer device-token" })
+	defer api.Close()
+
+	cfg := &gohttp.OAuth2Config{
+		ClientID:      "my-tv",
+		DeviceAuthURL: auth.URL + "/device",
+		TokenURL:      auth.URL + "/token",
+		Scopes:        []string{"profile"},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var code *gohttp.DeviceCode
+	src, err := cfg.AuthorizeDevice(ctx, func(dc *gohttp.DeviceCode) error {
+		code = dc
+		return nil
+	})
+	if assert.NoError(err) {
+		assert.Equal("WDJB-MJHT", code.UserCode)
+		assert.Equal("https://example.com/device", code.VerificationURI)
+		assert.WithinDuration(time.Now().Add(10*time.Minute), code.Expiry, 5*time.Second)
+		assert.Equal(3, *polled, "pending responses should be polled again")
+		assert.Equal([]time.Duration{5 * time.Second, 5 * time.Second, 5 * time.Second}, intervals,
+			"zero interval should be replaced by the default one")
+
+		resp, err := gohttp.New().TokenSource(src).Get(api.URL)
+		assert.NoError(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestAuthorizeDeviceSlowDown(t *testing.T) {
+	assert := assert.New(t)
+
+	var intervals []time.Duration
+	defer fastDeviceWait(&intervals)()
+	auth, polled := newDeviceServer(3, "", "slow_down", "authorization_pending", "slow_down")
+	defer auth.Close()
+
+	cfg := &gohttp.OAuth2Config{
+		ClientID:      "my-tv",
+		DeviceAuthURL: auth.URL + "/device",
+		TokenURL:      auth.URL + "/token",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token, err := cfg.DeviceToken(ctx, func(*gohttp.DeviceCode) error { return nil }, "profile")
+	if assert.NoError(err) {
+		assert.Equal("device-token", token.AccessToken)
+		assert.Equal(4, *polled)
+		assert.Equal([]time.Duration{5 * time.Second, 10 * time.Second, 10 * time.Second, 15 * time.Second}, intervals,
+			"every slow_down should add 5 seconds to polling interval")
+	}
+}
+
+func TestAuthorizeDeviceDenied(t *testing.T) {
+	assert := assert.New(t)
+
+	var intervals []time.Duration
+	defer fastDeviceWait(&intervals)()
+	auth, _ := newDeviceServer(1, "access_denied")
+	defer auth.Close()
+
+	cfg := &gohttp.OAuth2Config{
+		ClientID:      "my-tv",
+		DeviceAuthURL: auth.URL + "/device",
+		TokenURL:      auth.URL + "/token",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cfg.DeviceToken(ctx, func(*gohttp.DeviceCode) error { return nil }, "profile")
+	if assert.Error(err) {
+		oauthErr, ok := err.(*gohttp.OAuth2Error)
+		assert.True(ok)
+		assert.Equal("access_denied", oauthErr.Code)
+	}
+}
+
+func TestAuthorizeDeviceCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	var intervals []time.Duration
+	defer fastDeviceWait(&intervals)()
+	// user never finishes authorization
+	auth, _ := newDeviceServer(1<<30, "")
+	defer auth.Close()
+
+	cfg := &gohttp.OAuth2Config{
+		ClientID:      "my-tv",
+		DeviceAuthURL: auth.URL + "/device",
+		TokenURL:      auth.URL + "/token",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := cfg.DeviceToken(ctx, func(*gohttp.DeviceCode) error { return nil }, "profile")
+	assert.Equal(context.DeadlineExceeded, err)
+
+	cfg.DeviceAuthURL = ""
+	_, err = cfg.DeviceToken(ctx, func(*gohttp.DeviceCode) error { return nil })
+	assert.Error(err, "device authorization url is required")
+}
+
+func TestAuthorizeDeviceCanceledWhilePolling(t *testing.T) {
+	assert := assert.New(t)
+
+	var intervals []time.Duration
+	defer fastDeviceWait(&intervals)()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"device_code":"dev-1","user_code":"WDJB-MJHT","verification_uri":"https://example.com/device"}`)
+	})
+	// token endpoint hangs until the request is aborted
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	auth := httptest.NewServer(mux)
+	defer auth.Close()
+
+	cfg := &gohttp.OAuth2Config{
+		ClientID:      "my-tv",
+		DeviceAuthURL: auth.URL + "/device",
+		TokenURL:      auth.URL + "/token",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := cfg.DeviceToken(ctx, func(*gohttp.DeviceCode) error { return nil })
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.True(time.Since(start) < time.Second, "request in flight should be aborted by context")
+}
@@ -0,0 +1,193 @@
+package gohttp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultLoopbackRedirect is used when `OAuth2Config.RedirectURL` is empty,
+// port zero means a random free port is picked.
+const defaultLoopbackRedirect = "http://127.0.0.1:0/callback"
+
+// loopbackPage is shown in browser after authorization is done.
+const loopbackPage = `<html><body><p>%s</p><p>You can close this window and return to the application.</p></body></html>`
+
+// authResult is what the loopback server gets from the authorization redirect.
+type authResult struct {
+	code string
+	err  error
+}
+
+// AuthorizeLoopback runs OAuth2 authorization code flow with PKCE for native applications,
+// see RFC 8252 and RFC 7636, and returns a client which sends requests with the token.
+// The token is refreshed automatically if server issues a refresh token.
+//
+// It starts a temporary HTTP server on loopback interface to receive the authorization redirect,
+// `RedirectURL` decides the address and path it listens on, default value is
+// `http://127.0.0.1:0/callback`, which uses a random port.
+// `open` is called with the authorization url, usually it opens the url in a browser, or
+// prints it for user to open. Waiting for user and the code exchange stop when `ctx` is done.
+//
+// Usage:
+//    cfg := &gohttp.OAuth2Config{
+//        ClientID: "my-cli",
+//        AuthURL:  "https://auth.example.com/authorize",
+//        TokenURL: "https://auth.example.com/token",
+//    }
+//    c, err := cfg.AuthorizeLoopback(ctx, func(authURL string) error {
+//        fmt.Println("Open this url in your browser:", authURL)
+//        return nil
+//    }, "profile")
+func (cfg *OAuth2Config) AuthorizeLoopback(ctx context.Context, open func(authURL string) error, scopes ...string) (*Client, error) {
+	token, err := cfg.LoopbackToken(ctx, open, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	return New().TokenSource(cfg.TokenSource(token)), nil
+}
+
+// LoopbackToken works the same way as `AuthorizeLoopback`, except it returns the token,
+// so that it can be stored for later use.
+func (cfg *OAuth2Config) LoopbackToken(ctx context.Context, open func(authURL string) error, scopes ...string) (*Token, error) {
+	if len(scopes) == 0 {
+		scopes = cfg.Scopes
+	}
+
+	redirect := cfg.RedirectURL
+	if redirect == "" {
+		redirect = defaultLoopbackRedirect
+	}
+	redirectURL, err := url.Parse(redirect)
+	if err != nil {
+		return nil, err
+	}
+	if redirectURL.Scheme != "http" || !isLoopbackHost(redirectURL.Hostname()) {
+		return nil, fmt.Errorf("oauth2: redirect url %q is not a loopback http url", redirect)
+	}
+
+	listener, err := net.Listen("tcp", redirectURL.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	// the actual port is known only after listening
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	redirectURL.Host = net.JoinHostPort(redirectURL.Hostname(), port)
+	if redirectURL.Path == "" {
+		redirectURL.Path = "/"
+	}
+	redirectURI := redirectURL.String()
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := url.Parse(cfg.AuthURL)
+	if err != nil {
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", cfg.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	if scope := strings.Join(scopes, " "); scope != "" {
+		q.Set("scope", scope)
+	}
+	authURL.RawQuery = q.Encode()
+
+	results := make(chan authResult, 1)
+	server := &http.Server{Handler: loopbackHandler(redirectURL.Path, state, results)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	if err := open(authURL.String()); err != nil {
+		return nil, err
+	}
+
+	var result authResult
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	return cfg.retrieveToken(ctx, &tokenRequest{
+		GrantType:    "authorization_code",
+		Code:         result.code,
+		RedirectURI:  redirectURI,
+		CodeVerifier: verifier,
+	})
+}
+
+// loopbackHandler receives the authorization redirect, and sends the result to `results`.
+func loopbackHandler(path, state string, results chan<- authResult) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			// not the redirect we are waiting for, might be forged
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, loopbackPage, "Invalid state parameter.")
+			return
+		}
+
+		result := authResult{code: q.Get("code")}
+		if code := q.Get("error"); code != "" {
+			result.err = &OAuth2Error{Code: code, Description: q.Get("error_description"), URI: q.Get("error_uri")}
+			fmt.Fprintf(w, loopbackPage, "Authorization failed: "+code)
+		} else if result.code == "" {
+			result.err = errors.New("oauth2: authorization redirect missing code")
+			fmt.Fprintf(w, loopbackPage, "Authorization failed.")
+		} else {
+			fmt.Fprintf(w, loopbackPage, "Authorization succeeded.")
+		}
+
+		select {
+		case results <- result:
+		default:
+			// result is already received
+		}
+	})
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// randomString returns n random bytes encoded in url-safe base64 without padding.
+func randomString(n int) (string, error) {
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
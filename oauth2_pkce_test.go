@@ -0,0 +1,125 @@
+package gohttp_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// newPKCEServer returns an authorization server which issues a token only when
+// the code verifier matches the challenge sent in authorization request.
+func newPKCEServer(challenges map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("grant_type") != "authorization_code" ||
+			challenges[r.PostForm.Get("code")] != base64.RawURLEncoding.EncodeToString(sum[:]) ||
+			r.PostForm.Get("redirect_uri") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"pkce-token","token_type":"Bearer","expires_in":3600}`)
+	}))
+}
+
+// browser follows the authorization url, and redirects back as if user approved the request.
+func browser(challenges map[string]string, approve bool) func(string) error {
+	return func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+			return fmt.Errorf("bad authorization request: %s", authURL)
+		}
+		challenges["code-1"] = q.Get("code_challenge")
+
+		redirect, _ := url.Parse(q.Get("redirect_uri"))
+		params := url.Values{"state": {q.Get("state")}}
+		if approve {
+			params.Set("code", "code-1")
+		} else {
+			params.Set("error", "access_denied")
+		}
+		redirect.RawQuery = params.Encode()
+		go gohttp.Get(redirect.String())
+		return nil
+	}
+}
+
+func TestAuthorizeLoopback(t *testing.T) {
+	assert := assert.New(t)
+
+	challenges := map[string]string{}
+	auth := newPKCEServer(challenges)
+	defer auth.Close()
+	api := newAPIServer(func(auth string) bool { return auth == "Bearer pkce-token" })
+	defer api.Close()
+
+	cfg := &gohttp.OAuth2Config{
+		ClientID: "my-cli",
+		AuthURL:  auth.URL + "/authorize",
+		TokenURL: auth.URL + "/token",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := cfg.AuthorizeLoopback(ctx, browser(challenges, true), "profile")
+	if assert.NoError(err) {
+		resp, err := c.Get(api.URL)
+		assert.NoError(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestAuthorizeLoopbackDenied(t *testing.T) {
+	assert := assert.New(t)
+
+	challenges := map[string]string{}
+	auth := newPKCEServer(challenges)
+	defer auth.Close()
+
+	cfg := &gohttp.OAuth2Config{
+		ClientID:    "my-cli",
+		AuthURL:     auth.URL + "/authorize",
+		TokenURL:    auth.URL + "/token",
+		RedirectURL: "http://localhost:0/oauth/done",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cfg.LoopbackToken(ctx, browser(challenges, false))
+	if assert.Error(err) {
+		oauthErr, ok := err.(*gohttp.OAuth2Error)
+		assert.True(ok)
+		assert.Equal("access_denied", oauthErr.Code)
+	}
+}
+
+func TestAuthorizeLoopbackTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := &gohttp.OAuth2Config{ClientID: "my-cli", AuthURL: "http://auth.example.com/authorize"}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// user never finishes authorization
+	_, err := cfg.LoopbackToken(ctx, func(string) error { return nil })
+	assert.Equal(context.DeadlineExceeded, err)
+
+	cfg.RedirectURL = "http://example.com/callback"
+	_, err = cfg.LoopbackToken(ctx, func(string) error { return nil })
+	assert.Error(err, "non-loopback redirect url should be rejected")
+}
@@ -0,0 +1,206 @@
+package gohttp_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// tokenServer is a tiny OAuth2 authorization server for tests.
+type tokenServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	issued   int
+	requests []map[string]string
+}
+
+func newTokenServer(expiresIn int) *tokenServer {
+	s := &tokenServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form := map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		if id, secret, ok := r.BasicAuth(); ok {
+			form["basic"] = id + ":" + secret
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, form)
+		s.issued++
+		issued := s.issued
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if form["grant_type"] == "refresh_token" && form["refresh_token"] != "refresh-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant","error_description":"refresh token revoked"}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("access-%d", issued),
+			"token_type":    "bearer",
+			"expires_in":    expiresIn,
+			"refresh_token": "refresh-1",
+		})
+	}))
+	return s
+}
+
+// newAPIServer echoes `Authorization` header, or returns 401 if the token is not accepted.
+func newAPIServer(accept func(auth string) bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !accept(auth) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, auth)
+	}))
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	auth := newTokenServer(3600)
+	defer auth.Close()
+	api := newAPIServer(func(string) bool { return true })
+	defer api.Close()
+
+	cfg := &gohttp.OAuth2Config{
+		ClientID:     "my-service",
+		ClientSecret: "s3cret",
+		TokenURL:     auth.URL,
+	}
+
+	c := gohttp.New().URL(api.URL).TokenSource(cfg.ClientCredentials("orders:write", "orders:read"))
+	for i := 0; i < 2; i++ {
+		resp, err := c.New().Get()
+		assert.NoError(err)
+		data, _ := resp.AsString()
+		assert.Equal("Bearer access-1", data)
+	}
+
+	// same scope set in different order shares the cached token
+	resp, _ := gohttp.New().TokenSource(cfg.ClientCredentials("orders:read", "orders:write")).Get(api.URL)
+	data, _ := resp.AsString()
+	assert.Equal("Bearer access-1", data)
+
+	// different scope set gets its own token
+	resp, _ = gohttp.New().TokenSource(cfg.ClientCredentials("users:read")).Get(api.URL)
+	data, _ = resp.AsString()
+	assert.Equal("Bearer access-2", data)
+
+	if assert.Len(auth.requests, 2) {
+		assert.Equal("client_credentials", auth.requests[0]["grant_type"])
+		assert.Equal("orders:read orders:write", auth.requests[0]["scope"])
+		assert.Equal("my-service:s3cret", auth.requests[0]["basic"])
+		assert.Equal("users:read", auth.requests[1]["scope"])
+	}
+}
+
+func TestOAuth2AuthInParams(t *testing.T) {
+	assert := assert.New(t)
+
+	auth := newTokenServer(3600)
+	defer auth.Close()
+
+	cfg := &gohttp.OAuth2Config{
+		ClientID:     "my-service",
+		ClientSecret: "s3cret",
+		TokenURL:     auth.URL,
+		Scopes:       []string{"default"},
+		AuthInParams: true,
+	}
+	token, err := cfg.ClientCredentials().Token()
+	assert.NoError(err)
+	assert.Equal("access-1", token.AccessToken)
+	assert.WithinDuration(time.Now().Add(time.Hour), token.Expiry, 5*time.Second)
+
+	req := auth.requests[0]
+	assert.Equal("my-service", req["client_id"])
+	assert.Equal("s3cret", req["client_secret"])
+	assert.Equal("default", req["scope"])
+	assert.Empty(req["basic"])
+}
+
+func TestOAuth2RefreshToken(t *testing.T) {
+	assert := assert.New(t)
+
+	auth := newTokenServer(3600)
+	defer auth.Close()
+	api := newAPIServer(func(string) bool { return true })
+	defer api.Close()
+
+	cfg := &gohttp.OAuth2Config{ClientID: "cli", TokenURL: auth.URL}
+	expired := &gohttp.Token{
+		AccessToken:  "old",
+		RefreshToken: "refresh-1",
+		Expiry:       time.Now().Add(-time.Minute),
+	}
+
+	resp, err := gohttp.New().TokenSource(cfg.TokenSource(expired)).Get(api.URL)
+	assert.NoError(err)
+	data, _ := resp.AsString()
+	assert.Equal("Bearer access-1", data)
+
+	if assert.Len(auth.requests, 1) {
+		assert.Equal("refresh_token", auth.requests[0]["grant_type"])
+		assert.Equal("refresh-1", auth.requests[0]["refresh_token"])
+		assert.Equal("cli", auth.requests[0]["client_id"], "public client should send client id in body")
+	}
+}
+
+func TestOAuth2RefreshOnUnauthorized(t *testing.T) {
+	assert := assert.New(t)
+
+	auth := newTokenServer(3600)
+	defer auth.Close()
+	api := newAPIServer(func(auth string) bool { return auth != "Bearer revoked" })
+	defer api.Close()
+
+	cfg := &gohttp.OAuth2Config{ClientID: "cli", TokenURL: auth.URL}
+	valid := &gohttp.Token{
+		AccessToken:  "revoked",
+		RefreshToken: "refresh-1",
+		Expiry:       time.Now().Add(time.Hour),
+	}
+
+	resp, err := gohttp.New().TokenSource(cfg.TokenSource(valid)).Get(api.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	data, _ := resp.AsString()
+	assert.Equal("Bearer access-1", data)
+}
+
+func TestOAuth2Error(t *testing.T) {
+	assert := assert.New(t)
+
+	auth := newTokenServer(3600)
+	defer auth.Close()
+
+	cfg := &gohttp.OAuth2Config{ClientID: "cli", TokenURL: auth.URL}
+	expired := &gohttp.Token{AccessToken: "old", RefreshToken: "stolen"}
+	expired.Expiry = time.Now().Add(-time.Minute)
+
+	_, err := cfg.TokenSource(expired).Token()
+	if assert.Error(err) {
+		oauthErr, ok := err.(*gohttp.OAuth2Error)
+		if assert.True(ok, "error should be *OAuth2Error") {
+			assert.Equal("invalid_grant", oauthErr.Code)
+			assert.Equal(http.StatusBadRequest, oauthErr.StatusCode)
+			assert.True(strings.Contains(err.Error(), "refresh token revoked"))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package gohttp
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// PinError is returned when none of the certificates presented by server matches the pins of the host.
+type PinError struct {
+	// Host is the server name the pins are configured for
+	Host string
+
+	// Pins are the expected base64 encoded SHA-256 hashes of subject public key info
+	Pins []string
+
+	// Hashes are the hashes of certificates presented by server, leaf first
+	Hashes []string
+}
+
+func (e *PinError) Error() string {
+	return fmt.Sprintf("gohttp: public key pin mismatch for %s: got %s, want one of %s",
+		e.Host, strings.Join(e.Hashes, ", "), strings.Join(e.Pins, ", "))
+}
+
+// PinPublicKey pins the public keys of host: TLS handshake fails with `*PinError`, unless a certificate
+// in the verified chain has one of the given keys. Pins are base64 encoded SHA-256 hashes of subject
+// public key info, the same format as HPKP, which can be computed by:
+//    openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+//
+// Pinning the key of an intermediate or root CA survives leaf certificate renewal. With `InsecureSkipVerify`
+// there is no verified chain, only the key of the server certificate is checked. It is recommended
+// to add backup pins, like the key of the next certificate, so that key rotation does not lock clients out.
+// Calling it again with the same host adds more pins.
+//
+// Usage:
+//    gohttp.New().PinPublicKey("payments.example.com", "r/mIkG3eEpVdm+u/ko/cwxzOMo1bk4TyHIlByibiA5E=", backupPin)
+func (c *Client) PinPublicKey(host string, pins ...string) *Client {
+	s := c.tlsOptions()
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	s.pins[host] = append(s.pins[host], pins...)
+	return c
+}
+
+// PinReportOnly sets whether pin mismatches only get logged in debug mode, instead of failing the connection.
+// It is useful to check pins before enforcing them.
+func (c *Client) PinReportOnly(reportOnly bool) *Client {
+	c.tlsOptions().pinReportOnly = reportOnly
+	return c
+}
+
+// verifyPins checks the connection against pins of the server, it is used as `tls.Config.VerifyConnection`.
+func (s *tlsSettings) verifyPins(cs tls.ConnectionState, logger *log.Logger) error {
+	if len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+
+	host := strings.ToLower(cs.ServerName)
+	pins, ok := s.pins[host]
+	if !ok && host == "" {
+		// no server name is sent for IP addresses, find the pins by IP addresses of the certificate
+		for _, ip := range cs.PeerCertificates[0].IPAddresses {
+			if pins, ok = s.pins[ip.String()]; ok {
+				host = ip.String()
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil
+	}
+
+	// verified chains are empty if verification is skipped. Other certificates server sends are
+	// not verified and can be anyone's, only the leaf one is proved by the handshake.
+	chains := cs.VerifiedChains
+	if len(chains) == 0 {
+		chains = [][]*x509.Certificate{cs.PeerCertificates[:1]}
+	}
+	var hashes []string
+	for _, chain := range chains {
+		for _, cert := range chain {
+			hash := spkiHash(cert)
+			for _, pin := range pins {
+				if hash == pin {
+					return nil
+				}
+			}
+			hashes = append(hashes, hash)
+		}
+	}
+
+	err := &PinError{Host: host, Pins: pins, Hashes: hashes}
+	if s.pinReportOnly {
+		if logger != nil {
+			logger.Printf("%v (report only)\n", err)
+		}
+		return nil
+	}
+	return err
+}
+
+// spkiHash returns base64 encoded SHA-256 hash of certificate's subject public key info.
+func spkiHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
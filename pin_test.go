@@ -0,0 +1,174 @@
+package gohttp_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+func pinOf(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// serverCert issues a certificate of 127.0.0.1 for TLS servers, `chain` is appended to the certificate sent.
+func (ca *testCA) serverCert(t *testing.T, chain ...*x509.Certificate) tls.Certificate {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	return cert
+}
+
+// newPinServer returns a TLS server presenting cert.
+func newPinServer(cert tls.Certificate) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	ts.StartTLS()
+	return ts
+}
+
+func TestPinPublicKey(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	host := u.Hostname()
+	pin := pinOf(ts.Certificate())
+	wrongPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	c := gohttp.New().URL(ts.URL).InsecureSkipVerify(true)
+
+	resp, err := c.New().PinPublicKey(host, pin).Get()
+	if assert.NoError(err) {
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+
+	_, err = c.New().PinPublicKey(host, wrongPin, pin).Get()
+	assert.NoError(err, "backup pin should match")
+
+	_, err = c.New().PinPublicKey(host, wrongPin).Get()
+	var pinErr *gohttp.PinError
+	if assert.True(errors.As(err, &pinErr), "error should be *PinError: %v", err) {
+		assert.Equal(host, pinErr.Host)
+		assert.Equal([]string{wrongPin}, pinErr.Pins)
+		assert.Equal([]string{pin}, pinErr.Hashes)
+	}
+
+	_, err = c.New().PinPublicKey("other.example.com", wrongPin).Get()
+	assert.NoError(err, "pins of other hosts should not apply")
+
+	_, err = c.New().PinPublicKey(host, wrongPin).PinReportOnly(true).Get()
+	assert.NoError(err, "pin mismatch should only be reported")
+
+	// pins of a client do not leak to its parent
+	_, err = c.Get()
+	assert.NoError(err)
+}
+
+func TestPinReportOnlyLogger(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	wrongPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	var quiet, verbose bytes.Buffer
+	c := gohttp.New().URL(ts.URL).InsecureSkipVerify(true).PinPublicKey(u.Hostname(), wrongPin).PinReportOnly(true).Debug(false)
+	gohttp.SetLogOutput(c, &quiet)
+	_, err := c.New().Get()
+	assert.NoError(err)
+
+	// mismatches are logged by the client sending the request, not the one connected first
+	d := c.New().Debug(true)
+	gohttp.SetLogOutput(d, &verbose)
+	_, err = d.New().Get()
+	assert.NoError(err)
+
+	assert.Empty(quiet.String(), "nothing is logged out of debug mode")
+	assert.True(strings.Contains(verbose.String(), "(report only)"), "mismatch should be logged: %s", verbose.String())
+}
+
+func TestPinVerifiedChain(t *testing.T) {
+	assert := assert.New(t)
+
+	ca := newTestCA()
+	ts := newPinServer(ca.serverCert(t))
+	defer ts.Close()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", ca.cert.Raw)
+	wrongPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	c := gohttp.New().URL(ts.URL).RootCAs(caFile)
+
+	_, err := c.New().PinPublicKey("127.0.0.1", pinOf(ca.cert)).Get()
+	assert.NoError(err, "CA key in the verified chain should match")
+
+	_, err = c.New().PinPublicKey("127.0.0.1", pinOf(ts.Certificate())).Get()
+	assert.NoError(err, "server key should match")
+
+	_, err = c.New().PinPublicKey("127.0.0.1", wrongPin).Get()
+	var pinErr *gohttp.PinError
+	if assert.True(errors.As(err, &pinErr), "error should be *PinError: %v", err) {
+		assert.Equal([]string{pinOf(ts.Certificate()), pinOf(ca.cert)}, pinErr.Hashes)
+	}
+}
+
+func TestPinUnverifiedChain(t *testing.T) {
+	assert := assert.New(t)
+
+	// the server holds the key of its own certificate only, and sends the pinned one, which is public
+	genuine := newTestCA()
+	ts := newPinServer(newTestCA().serverCert(t, genuine.cert))
+	defer ts.Close()
+
+	c := gohttp.New().URL(ts.URL).InsecureSkipVerify(true)
+
+	_, err := c.New().PinPublicKey("127.0.0.1", pinOf(genuine.cert)).Get()
+	var pinErr *gohttp.PinError
+	if assert.True(errors.As(err, &pinErr), "unverified certificates should not match: %v", err) {
+		assert.Equal([]string{pinOf(ts.Certificate())}, pinErr.Hashes)
+	}
+
+	_, err = c.New().PinPublicKey("127.0.0.1", pinOf(ts.Certificate())).Get()
+	assert.NoError(err, "server key should match")
+}
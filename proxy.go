@@ -0,0 +1,247 @@
+package gohttp
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// proxyRule sends requests to matching hosts through proxy.
+type proxyRule struct {
+	proxy *url.URL
+	hosts []hostPattern
+}
+
+// hostPattern is an entry of host list, with the same semantics as `NO_PROXY` environment variable:
+//    *                 matches all hosts
+//    example.com       matches example.com and its subdomains
+//    .example.com      matches subdomains of example.com only, same as *.example.com
+//    10.0.0.1, ::1     matches the IP address
+//    10.0.0.0/8        matches IP addresses in the range
+//    example.com:8443  any of above with a port, matches the port only
+type hostPattern struct {
+	all            bool
+	ip             net.IP
+	cidr           *net.IPNet
+	domain         string
+	subdomainsOnly bool
+	port           string
+}
+
+// parseHostPatterns parses host list entries, each of them can also be a comma separated list.
+func parseHostPatterns(entries ...string) []hostPattern {
+	var patterns []hostPattern
+	for _, entry := range entries {
+		for _, field := range strings.Split(entry, ",") {
+			field = strings.ToLower(strings.TrimSpace(field))
+			if field == "" {
+				continue
+			}
+			patterns = append(patterns, parseHostPattern(field))
+		}
+	}
+	return patterns
+}
+
+func parseHostPattern(entry string) hostPattern {
+	if entry == "*" {
+		return hostPattern{all: true}
+	}
+	if _, cidr, err := net.ParseCIDR(entry); err == nil {
+		return hostPattern{cidr: cidr}
+	}
+
+	p := hostPattern{}
+	if host, port, err := net.SplitHostPort(entry); err == nil {
+		entry, p.port = host, port
+	}
+	entry = strings.Trim(entry, "[]")
+	if ip := net.ParseIP(entry); ip != nil {
+		p.ip = ip
+		return p
+	}
+
+	entry = strings.TrimPrefix(entry, "*")
+	if strings.HasPrefix(entry, ".") {
+		p.subdomainsOnly = true
+	}
+	p.domain = strings.TrimSuffix(strings.TrimPrefix(entry, "."), ".")
+	return p
+}
+
+// match reports whether host and port, which is never empty, match the pattern.
+func (p hostPattern) match(host, port string) bool {
+	if p.all {
+		return true
+	}
+	if p.port != "" && p.port != port {
+		return false
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if p.ip != nil || p.cidr != nil {
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return false
+		}
+		if p.cidr != nil {
+			return p.cidr.Contains(ip)
+		}
+		return p.ip.Equal(ip)
+	}
+	if host == p.domain && !p.subdomainsOnly {
+		return true
+	}
+	return strings.HasSuffix(host, "."+p.domain)
+}
+
+// matchHost reports whether any of patterns matches host and port.
+func matchHost(patterns []hostPattern, host, port string) bool {
+	for _, p := range patterns {
+		if p.match(host, port) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProxyFor sends requests to the hosts through the proxy, instead of the default one set by `Proxy`.
+// Hosts have the same format as `NO_PROXY` environment variable: `example.com` matches the domain
+// and its subdomains, `.example.com` or `*.example.com` only matches subdomains, IP addresses and
+// CIDR ranges match IP hosts, and each of them can have a port. Rules are checked in the order they
+// are added, the first matching one is used.
+//
+// Proxies are resolved for each request, HTTP(S) and SOCKS5 proxies can be mixed.
+//
+// Usage:
+//    gohttp.New().
+//        Proxy("http://proxy.example.com:3128").
+//        ProxyFor("socks5h://127.0.0.1:1080", "*.internal.example.com", "10.0.0.0/8").
+//        NoProxy("localhost", "127.0.0.1").
+//        Get("https://api.internal.example.com/status")
+func (c *Client) ProxyFor(proxy string, hosts ...string) *Client {
+	s := c.dialOptions()
+	s.proxyRules = append(s.proxyRules, proxyRule{proxy: s.parseProxy(proxy), hosts: parseHostPatterns(hosts...)})
+	return c
+}
+
+// NoProxy sets the hosts requests go to directly, bypassing all proxies. Hosts have the same format
+// as `NO_PROXY` environment variable, see `ProxyFor`, entries can also be comma separated. For example,
+// `NoProxy(os.Getenv("NO_PROXY"))` honors the environment variable.
+func (c *Client) NoProxy(hosts ...string) *Client {
+	s := c.dialOptions()
+	s.noProxy = append(s.noProxy, parseHostPatterns(hosts...)...)
+	return c
+}
+
+// ProxyAuth sets the credentials of proxies which have no username and password in their urls.
+// They are sent by basic auth to HTTP proxies, and by username/password authentication to SOCKS5 proxies.
+func (c *Client) ProxyAuth(username, password string) *Client {
+	c.dialOptions().proxyAuth = url.UserPassword(username, password)
+	return c
+}
+
+// ProxyConnectHeader sets a header sent to HTTP proxies in `CONNECT` requests, which tunnel HTTPS requests.
+func (c *Client) ProxyConnectHeader(key, value string) *Client {
+	c.dialOptions().connectHeader.Set(key, value)
+	return c
+}
+
+// parseProxy parses proxy url, the error is kept to be returned by requests.
+func (s *dialSettings) parseProxy(proxy string) *url.URL {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		s.setErr(err)
+		return nil
+	}
+	return u
+}
+
+// proxies returns all proxies of the settings.
+func (s *dialSettings) proxies() []*url.URL {
+	var proxies []*url.URL
+	if s.proxy != nil {
+		proxies = append(proxies, s.proxy)
+	}
+	for _, rule := range s.proxyRules {
+		if rule.proxy != nil {
+			proxies = append(proxies, rule.proxy)
+		}
+	}
+	return proxies
+}
+
+// proxyForHost finds the proxy for host and port, nil means direct connection.
+// `matched` is false if none of the settings applies to the host.
+func (s *dialSettings) proxyForHost(host, port string) (proxy *url.URL, matched bool) {
+	if matchHost(s.noProxy, host, port) {
+		return nil, true
+	}
+	for _, rule := range s.proxyRules {
+		if matchHost(rule.hosts, host, port) {
+			return rule.proxy, true
+		}
+	}
+	if s.proxy != nil {
+		return s.proxy, true
+	}
+	return nil, false
+}
+
+// proxyFunc returns the `http.Transport.Proxy` function, `fallback` is the proxy function of
+// base transport, used for requests none of the settings applies to.
+func (s *dialSettings) proxyFunc(fallback func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if s.unixSocket != "" {
+			return nil, nil
+		}
+		proxy, matched := s.proxyForHost(req.URL.Hostname(), urlPort(req.URL))
+		if !matched {
+			if fallback == nil {
+				return nil, nil
+			}
+			return fallback(req)
+		}
+		if proxy == nil || isSOCKSProxy(proxy) {
+			// SOCKS proxies are dialed by the dialer
+			return nil, nil
+		}
+		return s.withAuth(proxy), nil
+	}
+}
+
+// withAuth returns proxy url with the credentials of `ProxyAuth`, unless it has its own.
+func (s *dialSettings) withAuth(proxy *url.URL) *url.URL {
+	if s.proxyAuth == nil || proxy.User != nil {
+		return proxy
+	}
+	u := *proxy
+	u.User = s.proxyAuth
+	return &u
+}
+
+// isSOCKSProxy reports whether proxy is a SOCKS proxy, which is dialed by gohttp itself.
+func isSOCKSProxy(proxy *url.URL) bool {
+	scheme := strings.ToLower(proxy.Scheme)
+	return scheme == "socks5" || scheme == "socks5h"
+}
+
+// urlPort returns the port of url, or the default port of its scheme.
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return "443"
+	case "socks5", "socks5h":
+		return "1080"
+	}
+	return "80"
+}
+
+// canonicalAddr returns host:port of url, with the default port of its scheme if it has none.
+func canonicalAddr(u *url.URL) string {
+	return net.JoinHostPort(u.Hostname(), urlPort(u))
+}
@@ -0,0 +1,178 @@
+package gohttp_test
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// newFakeProxy returns an HTTP proxy which answers requests itself with its name and the target host.
+func newFakeProxy(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", name, r.URL.Host)
+	}))
+}
+
+func TestProxyRules(t *testing.T) {
+	assert := assert.New(t)
+
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "direct")
+	}))
+	defer direct.Close()
+	proxyA := newFakeProxy("A")
+	defer proxyA.Close()
+	proxyB := newFakeProxy("B")
+	defer proxyB.Close()
+
+	c := gohttp.New().
+		Proxy(proxyA.URL).
+		ProxyFor(proxyB.URL, "*.internal.test", "10.0.0.0/8", "example.test:8080").
+		NoProxy("127.0.0.1, localhost")
+
+	for target, expected := range map[string]string{
+		"http://api.internal.test/": "B api.internal.test",
+		"http://internal.test/":     "A internal.test",
+		"http://10.1.2.3/":          "B 10.1.2.3",
+		"http://example.test:8080/": "B example.test:8080",
+		"http://example.test/":      "A example.test",
+		"http://www.example.test/":  "A www.example.test",
+		direct.URL:                  "direct",
+	} {
+		resp, err := c.New().Get(target)
+		if assert.NoError(err, target) {
+			data, _ := resp.AsString()
+			assert.Equal(expected, data, target)
+		}
+	}
+
+	// rules of a cloned client do not affect the base client
+	resp, err := c.New().NoProxy("*").Get("http://api.internal.test/")
+	assert.Error(err, "request should go directly to a host which does not exist")
+	resp, err = c.Get("http://api.internal.test/")
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("B api.internal.test", data)
+	}
+
+	_, err = gohttp.New().ProxyFor("http://[::1", "example.test").Get(direct.URL)
+	assert.Error(err, "invalid proxy url should be reported")
+}
+
+func TestProxyAuthAndConnectHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "tunneled")
+	}))
+	defer ts.Close()
+
+	var auth, header string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		auth = r.Header.Get("Proxy-Authorization")
+		header = r.Header.Get("X-Tunnel-Tag")
+
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer target.Close()
+		w.WriteHeader(http.StatusOK)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		defer conn.Close()
+		go io.Copy(target, conn)
+		io.Copy(conn, target)
+	}))
+	defer proxy.Close()
+
+	resp, err := gohttp.New().
+		InsecureSkipVerify(true).
+		Proxy(proxy.URL).
+		ProxyAuth("alice", "s3cret").
+		ProxyConnectHeader("X-Tunnel-Tag", "batch-jobs").
+		Get(ts.URL)
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("tunneled", data)
+	}
+	assert.Equal("Basic YWxpY2U6czNjcmV0", auth)
+	assert.Equal("batch-jobs", header)
+}
+
+func TestProxyRulesWithSOCKS(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "direct")
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	httpProxy := newFakeProxy("http")
+	defer httpProxy.Close()
+	socks := newSOCKSServer(t, "tunnel", "s3cret")
+	defer socks.Close()
+
+	c := gohttp.New().
+		Proxy(httpProxy.URL).
+		ProxyFor("socks5h://"+socks.ln.Addr().String(), "localhost").
+		ProxyAuth("tunnel", "s3cret")
+
+	resp, err := c.New().Get("http://localhost:" + port)
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("direct", data)
+		assert.Equal("localhost:"+port, <-socks.targets)
+	}
+
+	resp, err = c.New().Get("http://example.test/")
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("http example.test", data)
+	}
+}
+
+func TestProxySettingsReuseConnections(t *testing.T) {
+	assert := assert.New(t)
+
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	// clones share proxy settings, but have different TLS options, and so different base transports
+	b := gohttp.New().URL(ts.URL).NoProxy("*")
+	c := b.New().MinTLSVersion(tls.VersionTLS12)
+	for i := 0; i < 3; i++ {
+		for _, client := range []*gohttp.Client{b, c} {
+			resp, err := client.New().Get()
+			if !assert.NoError(err) {
+				return
+			}
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+	}
+	assert.Equal(int32(2), atomic.LoadInt32(&conns), "each client should reuse its connection")
+}
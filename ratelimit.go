@@ -0,0 +1,139 @@
+package gohttp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is the rate limit status reported by server in response headers.
+// Both the common `X-RateLimit-*` headers and IETF `RateLimit-*` headers are supported.
+type RateLimit struct {
+	// Limit is the max number of requests allowed in current window, -1 if unknown.
+	Limit int
+
+	// Remaining is the number of requests left in current window, -1 if unknown.
+	Remaining int
+
+	// Reset is the time when current window resets, zero if unknown.
+	Reset time.Time
+
+	// Policy is the raw value of `RateLimit-Policy` or `X-RateLimit-Policy` header, if any.
+	Policy string
+}
+
+// unixTimeThreshold distinguishes a unix timestamp from a delay in seconds in reset headers.
+// Some servers send the time when window resets, some send seconds until it resets.
+const unixTimeThreshold = 1000000000
+
+// RateLimit parses rate limit headers of the response.
+// nil is returned if response contains none of them.
+//
+// Usage:
+//    resp, _ := gohttp.Get(url)
+//    if rl := resp.RateLimit(); rl != nil && rl.Remaining == 0 {
+//        time.Sleep(time.Until(rl.Reset))
+//    }
+func (resp *GoResponse) RateLimit() *RateLimit {
+	return parseRateLimit(resp.Header, time.Now())
+}
+
+func parseRateLimit(header http.Header, now time.Time) *RateLimit {
+	rl := &RateLimit{Limit: -1, Remaining: -1}
+	found := false
+
+	// draft IETF structured field: `RateLimit: limit=100, remaining=50, reset=30`
+	if value := header.Get("RateLimit"); value != "" {
+		for _, item := range strings.Split(value, ",") {
+			kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			n, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 64)
+			if err != nil {
+				continue
+			}
+			switch strings.ToLower(kv[0]) {
+			case "limit":
+				rl.Limit, found = int(n), true
+			case "remaining":
+				rl.Remaining, found = int(n), true
+			case "reset":
+				rl.Reset, found = now.Add(time.Duration(n)*time.Second), true
+			}
+		}
+	}
+
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		if n, ok := headerInt(header, prefix+"Limit"); ok && rl.Limit < 0 {
+			rl.Limit, found = int(n), true
+		}
+		if n, ok := headerInt(header, prefix+"Remaining"); ok && rl.Remaining < 0 {
+			rl.Remaining, found = int(n), true
+		}
+		if n, ok := headerInt(header, prefix+"Reset"); ok && rl.Reset.IsZero() {
+			if n >= unixTimeThreshold {
+				rl.Reset = time.Unix(n, 0)
+			} else {
+				rl.Reset = now.Add(time.Duration(n) * time.Second)
+			}
+			found = true
+		}
+		if policy := header.Get(prefix + "Policy"); policy != "" && rl.Policy == "" {
+			rl.Policy, found = policy, true
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	return rl
+}
+
+// headerInt reads an integer value from header, the first value is used if
+// header contains a list, for example `RateLimit-Limit: 100, 100;w=60`.
+func headerInt(header http.Header, key string) (int64, bool) {
+	value := header.Get(key)
+	if value == "" {
+		return 0, false
+	}
+	if i := strings.IndexAny(value, ",;"); i >= 0 {
+		value = value[:i]
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// parseRetryAfter parses `Retry-After` header of 429 and 503 responses,
+// value can be either seconds to wait or an HTTP-date.
+func parseRetryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
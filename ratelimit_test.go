@@ -0,0 +1,130 @@
+package gohttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert := assert.New(t)
+
+	var times []time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		if len(times) == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+	}))
+	defer ts.Close()
+
+	policy := &gohttp.RetryPolicy{
+		MaxAttempts:     2,
+		StatusCodes:     []int{http.StatusTooManyRequests},
+		InitialInterval: time.Millisecond,
+	}
+	resp, err := gohttp.New().RetryPolicy(policy).Get(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Len(times, 2)
+	assert.True(times[1].Sub(times[0]) >= time.Second, "should wait as long as Retry-After says")
+}
+
+func TestRetryAfterCapped(t *testing.T) {
+	assert := assert.New(t)
+
+	var times []time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		if len(times) == 1 {
+			w.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer ts.Close()
+
+	policy := &gohttp.RetryPolicy{
+		MaxAttempts:   2,
+		StatusCodes:   []int{http.StatusServiceUnavailable},
+		MaxRetryAfter: 50 * time.Millisecond,
+	}
+	resp, err := gohttp.New().RetryPolicy(policy).Get(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Len(times, 2)
+	elapsed := times[1].Sub(times[0])
+	assert.True(elapsed >= 50*time.Millisecond && elapsed < time.Second, "Retry-After should be capped")
+}
+
+func TestResponseRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	reset := time.Now().Add(time.Hour).Unix()
+	cases := []struct {
+		header    http.Header
+		limit     int
+		remaining int
+		reset     time.Time
+		policy    string
+	}{
+		{
+			header:    http.Header{"X-Ratelimit-Limit": {"5000"}, "X-Ratelimit-Remaining": {"4999"}, "X-Ratelimit-Reset": {strconv.FormatInt(reset, 10)}},
+			limit:     5000,
+			remaining: 4999,
+			reset:     time.Unix(reset, 0),
+		},
+		{
+			header:    http.Header{"Ratelimit-Limit": {"100"}, "Ratelimit-Remaining": {"0"}, "Ratelimit-Reset": {"30"}, "Ratelimit-Policy": {"100;w=60"}},
+			limit:     100,
+			remaining: 0,
+			reset:     time.Now().Add(30 * time.Second),
+			policy:    "100;w=60",
+		},
+		{
+			header:    http.Header{"Ratelimit": {"limit=10, remaining=3, reset=5"}},
+			limit:     10,
+			remaining: 3,
+			reset:     time.Now().Add(5 * time.Second),
+		},
+	}
+
+	for _, tc := range cases {
+		header := tc.header
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+		}))
+
+		resp, err := gohttp.Get(ts.URL)
+		assert.NoError(err)
+		rl := resp.RateLimit()
+		if assert.NotNil(rl) {
+			assert.Equal(tc.limit, rl.Limit)
+			assert.Equal(tc.remaining, rl.Remaining)
+			assert.WithinDuration(tc.reset, rl.Reset, 2*time.Second)
+			assert.Equal(tc.policy, rl.Policy)
+		}
+		ts.Close()
+	}
+}
+
+func TestResponseWithoutRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	resp, err := gohttp.Get(ts.URL)
+	assert.NoError(err)
+	assert.Nil(resp.RateLimit())
+}
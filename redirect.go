@@ -0,0 +1,142 @@
+package gohttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// DefaultMaxRedirects is the max number of redirects followed for one request,
+// which is the same as `net/http` client.
+const DefaultMaxRedirects = 10
+
+// sensitiveHeaders are request headers that carry credentials, they are not sent
+// to a different host on redirects unless user asks so.
+var sensitiveHeaders = []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2"}
+
+// RedirectPolicy decides whether a redirect should be followed, it works the same way as
+// `http.Client.CheckRedirect`: `req` is the upcoming request, `via` are the requests made
+// already, oldest first. Returning an error stops redirecting and the error is returned,
+// except `http.ErrUseLastResponse`, which returns the redirect response itself.
+type RedirectPolicy func(req *http.Request, via []*http.Request) error
+
+// Redirect is one hop of redirects made for a request.
+type Redirect struct {
+	// URL is the url of the request that got redirected
+	URL *url.URL
+
+	// StatusCode is the redirect response status code, like 301, 302
+	StatusCode int
+
+	// Header is the redirect response header, `Location` header tells where it goes
+	Header http.Header
+}
+
+// redirectHistoryKey is the context key of redirect history for a request
+type redirectHistoryKey struct{}
+
+// redirectHistory collects redirects followed by one attempt of a request.
+type redirectHistory struct {
+	hops []*Redirect
+}
+
+func redirectHistoryFrom(ctx context.Context) *redirectHistory {
+	history, _ := ctx.Value(redirectHistoryKey{}).(*redirectHistory)
+	return history
+}
+
+// History returns every redirect followed before the final response, oldest first.
+// Empty slice means no redirect happens.
+func (resp *GoResponse) History() []*Redirect {
+	return resp.history
+}
+
+// Redirects sets the max number of redirects followed for a request,
+// an error is returned if a request is redirected more times.
+// Default value is `DefaultMaxRedirects`.
+func (c *Client) Redirects(max int) *Client {
+	c.maxRedirects = max
+	c.noRedirects = false
+	return c
+}
+
+// NoRedirects makes the client not follow any redirects,
+// the redirect response itself is returned instead.
+func (c *Client) NoRedirects() *Client {
+	c.noRedirects = true
+	return c
+}
+
+// RedirectPolicy sets a custom function deciding whether a redirect should be followed.
+// It is called after the max redirects limit is checked.
+//
+// Usage:
+//    gohttp.New().RedirectPolicy(func(req *http.Request, via []*http.Request) error {
+//        if req.URL.Scheme != "https" {
+//            return errors.New("insecure redirect")
+//        }
+//        return nil
+//    })
+func (c *Client) RedirectPolicy(policy RedirectPolicy) *Client {
+	c.redirectPolicy = policy
+	return c
+}
+
+// KeepAuthOnRedirect sets whether `Authorization` header is kept when redirected to a different host.
+// By default, it is removed to avoid leaking credentials to a third party.
+func (c *Client) KeepAuthOnRedirect(keep bool) *Client {
+	c.keepAuthOnRedirect = keep
+	return c
+}
+
+// KeepCookiesOnRedirect sets whether cookies set by `Cookie` are kept when redirected to a different host.
+// By default, they are removed. Cookies from cookie jar are not affected, they are always sent
+// according to their domain.
+func (c *Client) KeepCookiesOnRedirect(keep bool) *Client {
+	c.keepCookiesOnRedirect = keep
+	return c
+}
+
+// checkRedirect is used as `http.Client.CheckRedirect`, it enforces redirect settings and
+// records redirect history.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if c.noRedirects {
+		return http.ErrUseLastResponse
+	}
+	if len(via) > c.maxRedirects {
+		return fmt.Errorf("gohttp: stopped after %d redirects", c.maxRedirects)
+	}
+
+	// `net/http` only strips sensitive headers when redirected out of the original domain,
+	// here any host change is considered, and user decides what to keep.
+	first := via[0]
+	if req.URL.Host != first.URL.Host {
+		for _, key := range sensitiveHeaders {
+			keep := c.keepAuthOnRedirect
+			if key == "Cookie" || key == "Cookie2" {
+				keep = c.keepCookiesOnRedirect
+			}
+			if keep && len(first.Header[key]) > 0 {
+				req.Header[key] = first.Header[key]
+			} else {
+				req.Header.Del(key)
+			}
+		}
+	}
+
+	if c.redirectPolicy != nil {
+		if err := c.redirectPolicy(req, via); err != nil {
+			return err
+		}
+	}
+
+	if history := redirectHistoryFrom(req.Context()); history != nil && req.Response != nil {
+		history.hops = append(history.hops, &Redirect{
+			URL:        via[len(via)-1].URL,
+			StatusCode: req.Response.StatusCode,
+			Header:     req.Response.Header,
+		})
+	}
+	return nil
+}
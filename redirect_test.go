@@ -0,0 +1,126 @@
+package gohttp_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// newRedirectServer returns a server redirecting `/n` to `/n-1`, until `/0` is reached.
+func newRedirectServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/%d", &n)
+		if n > 0 {
+			w.Header().Set("X-Hop", fmt.Sprint(n))
+			http.Redirect(w, r, fmt.Sprintf("/%d", n-1), http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "arrived")
+	}))
+}
+
+func TestRedirectHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newRedirectServer()
+	defer ts.Close()
+
+	resp, err := gohttp.New().Path("/3").Get(ts.URL)
+	assert.NoError(err)
+	data, _ := resp.AsString()
+	assert.Equal("arrived", data)
+
+	history := resp.History()
+	if assert.Len(history, 3) {
+		for i, hop := range history {
+			assert.Equal(fmt.Sprintf("/%d", 3-i), hop.URL.Path)
+			assert.Equal(http.StatusFound, hop.StatusCode)
+			assert.Equal(fmt.Sprint(3-i), hop.Header.Get("X-Hop"))
+		}
+	}
+
+	resp, err = gohttp.New().Path("/0").Get(ts.URL)
+	assert.NoError(err)
+	assert.Empty(resp.History())
+}
+
+func TestRedirectsLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newRedirectServer()
+	defer ts.Close()
+
+	_, err := gohttp.New().Redirects(2).Path("/3").Get(ts.URL)
+	assert.Error(err, "should stop after 2 redirects")
+	assert.True(strings.Contains(err.Error(), "stopped after 2 redirects"))
+
+	resp, err := gohttp.New().Redirects(3).Path("/3").Get(ts.URL)
+	assert.NoError(err)
+	assert.Len(resp.History(), 3)
+}
+
+func TestNoRedirects(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newRedirectServer()
+	defer ts.Close()
+
+	resp, err := gohttp.New().NoRedirects().Path("/3").Get(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusFound, resp.StatusCode)
+	assert.Equal("/2", resp.Header.Get("Location"))
+	assert.Empty(resp.History())
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newRedirectServer()
+	defer ts.Close()
+
+	policyErr := errors.New("no way to /1")
+	_, err := gohttp.New().RedirectPolicy(func(req *http.Request, via []*http.Request) error {
+		if req.URL.Path == "/1" {
+			return policyErr
+		}
+		return nil
+	}).Path("/3").Get(ts.URL)
+	assert.True(errors.Is(err, policyErr))
+}
+
+func TestRedirectCrossHostCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "auth=%s cookie=%s", r.Header.Get("Authorization"), r.Header.Get("Cookie"))
+	}))
+	defer target.Close()
+
+	// use `localhost` instead of `127.0.0.1`, so that it is considered as a different host
+	targetURL := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, targetURL, http.StatusFound)
+	}))
+	defer origin.Close()
+
+	cookie := &http.Cookie{Name: "session", Value: "secret"}
+
+	resp, err := gohttp.New().BasicAuth("user", "pass").Cookie(cookie).Get(origin.URL)
+	assert.NoError(err)
+	data, _ := resp.AsString()
+	assert.Equal("auth= cookie=", data, "credentials should be removed on cross host redirects")
+
+	resp, err = gohttp.New().BasicAuth("user", "pass").Cookie(cookie).
+		KeepAuthOnRedirect(true).KeepCookiesOnRedirect(true).Get(origin.URL)
+	assert.NoError(err)
+	data, _ = resp.AsString()
+	assert.Equal("auth=Basic dXNlcjpwYXNz cookie=session=secret", data)
+}
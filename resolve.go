@@ -0,0 +1,85 @@
+package gohttp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// connectRule redirects connections to host:port to another one, empty fields match any host or port,
+// and keep the original ones in target.
+type connectRule struct {
+	host, port             string
+	targetHost, targetPort string
+}
+
+// Resolve makes connections to hostPort go to IP address addr, like `curl --resolve`. Url, `Host`
+// header and TLS server name, which certificate is verified against, are still the original host.
+// It is useful to test a specific backend without editing /etc/hosts.
+//
+// Overrides apply to connections the client makes, requests through HTTP proxies connect to the proxy,
+// which resolves host itself.
+//
+// Usage:
+//    gohttp.New().Resolve("api.example.com:443", "10.0.3.17").Get("https://api.example.com/status")
+func (c *Client) Resolve(hostPort, addr string) *Client {
+	s := c.dialOptions()
+	host, port, err := net.SplitHostPort(hostPort)
+	ip := net.ParseIP(strings.Trim(addr, "[]"))
+	switch {
+	case err != nil || host == "" || port == "":
+		s.setErr(fmt.Errorf("gohttp: invalid resolve host %q, want host:port", hostPort))
+	case ip == nil:
+		s.setErr(fmt.Errorf("gohttp: invalid resolve address %q, want an IP address", addr))
+	default:
+		s.resolve[net.JoinHostPort(strings.ToLower(host), port)] = ip.String()
+	}
+	return c
+}
+
+// ConnectTo makes connections to host:port in `from` go to host:port in `to` instead, like `curl --connect-to`.
+// Empty host or port in `from` matches any, and in `to` keeps the original one, for example `ConnectTo(":443", ":8443")`
+// changes the port of all HTTPS connections. Url, `Host` header and TLS server name are still the original ones.
+// Rules are checked in the order they are added, the first matching one is used, then `Resolve` applies to the target.
+//
+// Usage:
+//    gohttp.New().ConnectTo("api.example.com:443", "green.internal.example.com:8443").Get("https://api.example.com/status")
+func (c *Client) ConnectTo(from, to string) *Client {
+	s := c.dialOptions()
+	host, port, err := net.SplitHostPort(from)
+	if err != nil {
+		s.setErr(fmt.Errorf("gohttp: invalid connect-to source %q, want host:port", from))
+		return c
+	}
+	targetHost, targetPort, err := net.SplitHostPort(to)
+	if err != nil {
+		s.setErr(fmt.Errorf("gohttp: invalid connect-to target %q, want host:port", to))
+		return c
+	}
+	s.connectTo = append(s.connectTo, connectRule{
+		host:       strings.ToLower(host),
+		port:       port,
+		targetHost: targetHost,
+		targetPort: targetPort,
+	})
+	return c
+}
+
+// overrideAddr returns the address to connect to for host and port, after `ConnectTo` and `Resolve` overrides.
+func (s *dialSettings) overrideAddr(host, port string) string {
+	for _, rule := range s.connectTo {
+		if (rule.host == "" || strings.EqualFold(rule.host, host)) && (rule.port == "" || rule.port == port) {
+			if rule.targetHost != "" {
+				host = rule.targetHost
+			}
+			if rule.targetPort != "" {
+				port = rule.targetPort
+			}
+			break
+		}
+	}
+	if addr, ok := s.resolve[net.JoinHostPort(strings.ToLower(host), port)]; ok {
+		host = addr
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,109 @@
+package gohttp_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// localTransport returns a transport which only dials IP addresses. Host names are reported by
+// the error instead of being looked up, so that tests never depend on DNS or the network.
+func localTransport() *http.Transport {
+	dialer := &net.Dialer{}
+	return &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			if net.ParseIP(host) == nil {
+				return nil, fmt.Errorf("unexpected dial to %s", addr)
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	// certificate of test server is valid for example.com
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Host, r.TLS.ServerName)
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	c := gohttp.New().Transport(localTransport()).RootCAs(serverCAFile(t, dir, ts))
+
+	resp, err := c.New().Resolve("example.com:"+port, "127.0.0.1").Get("https://example.com:" + port + "/")
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("example.com:"+port+" example.com", data)
+	}
+
+	_, err = c.New().Resolve("other.example.com:"+port, "127.0.0.1").Get("https://other.test:" + port + "/")
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "unexpected dial to other.test:"+port, "override of other hosts should not apply")
+	}
+
+	_, err = c.New().Resolve("example.com", "127.0.0.1").Get(ts.URL)
+	assert.Error(err, "host without port is invalid")
+	_, err = c.New().Resolve("example.com:443", "localhost").Get(ts.URL)
+	assert.Error(err, "address should be an IP address")
+}
+
+func TestConnectTo(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Host, r.TLS.ServerName)
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	c := gohttp.New().Transport(localTransport()).RootCAs(serverCAFile(t, dir, ts)).URL("https://example.com/")
+
+	resp, err := c.New().ConnectTo("example.com:443", "127.0.0.1:"+port).Get()
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("example.com example.com", data)
+	}
+
+	// empty source host matches any, empty target port keeps the original one, then Resolve applies
+	_, err = c.New().
+		ConnectTo(":443", "backend.test:").
+		ConnectTo("backend.test:443", ":"+port).
+		Get()
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "unexpected dial to backend.test:443", "only the first matching rule applies")
+	}
+
+	resp, err = c.New().
+		ConnectTo(":443", "backend.test:"+port).
+		Resolve("backend.test:"+port, "127.0.0.1").
+		Get()
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("example.com example.com", data)
+	}
+
+	// overrides of a cloned client do not affect the base client
+	_, err = c.Get()
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "unexpected dial to example.com:443", "base client should not connect to test server")
+	}
+
+	_, err = c.New().ConnectTo("example.com", ":"+port).Get()
+	assert.Error(err, "source without port is invalid")
+}
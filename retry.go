@@ -0,0 +1,171 @@
+package gohttp
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// DefaultMaxRetryAfter caps the waiting time requested by `Retry-After` header,
+// if `RetryPolicy.MaxRetryAfter` is not set.
+const DefaultMaxRetryAfter = time.Minute
+
+// RetryPolicy describes when and how a failed request is sent again.
+//
+// By default only transport errors (connection refused, timeout, etc.) are retried,
+// `StatusCodes` adds response status codes that are considered as failure too.
+// Waiting time between attempts grows exponentially from `InitialInterval` by `Multiplier`,
+// and never exceeds `MaxInterval`.
+// If a 429 or 503 response carries `Retry-After` header, the server specified time is waited instead.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Zero means using the value set by `Client.Retries`.
+	MaxAttempts int
+
+	// StatusCodes lists response status codes that should be retried, like 502, 503, 504 and 429.
+	StatusCodes []int
+
+	// InitialInterval is the waiting time before the first retry.
+	// Zero means retry immediately, which is what `Client.Retries` does.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the waiting time between two attempts, zero means no limit.
+	MaxInterval time.Duration
+
+	// Multiplier is the factor waiting time grows by after each attempt.
+	// Values less than 1 are treated as 2.
+	Multiplier float64
+
+	// Jitter randomizes each waiting time by up to this fraction,
+	// for example 0.2 means the actual waiting time is within [80%, 120%] of the computed one.
+	Jitter float64
+
+	// MaxElapsedTime limits the total time spent on all attempts, zero means no limit.
+	// No more retries will be made if waiting for the next attempt would exceed it.
+	MaxElapsedTime time.Duration
+
+	// MaxRetryAfter caps the waiting time requested by server with `Retry-After` header.
+	// Zero means `DefaultMaxRetryAfter`, negative value means ignoring `Retry-After` header.
+	MaxRetryAfter time.Duration
+
+	// ShouldRetry decides whether the request should be retried with the
+	// response or error of last attempt. If it is set, `StatusCodes` and the
+	// default error condition are ignored.
+	ShouldRetry func(resp *http.Response, err error) bool
+}
+
+// DefaultRetryPolicy returns a policy suitable for most flaky upstream services:
+// three attempts in total, retrying on transport errors and 429/502/503/504
+// responses with exponential backoff starting from 100ms.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		StatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     10 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxElapsedTime:  30 * time.Second,
+	}
+}
+
+// shouldRetry reports if the response or error of one attempt is considered as failure.
+func (p *RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(resp, err)
+	}
+	if err != nil {
+		return true
+	}
+	for _, code := range p.StatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff computes the waiting time after `tried` attempts have been made.
+func (p *RetryPolicy) backoff(tried int) time.Duration {
+	if p.InitialInterval <= 0 {
+		return 0
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	wait := float64(p.InitialInterval) * math.Pow(multiplier, float64(tried-1))
+	if p.MaxInterval > 0 && wait > float64(p.MaxInterval) {
+		wait = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		delta := p.Jitter * wait
+		wait = wait - delta + rand.Float64()*2*delta
+	}
+	return time.Duration(wait)
+}
+
+// wait returns how long to wait before next attempt, after `tried` attempts
+// have been made and the last one got `resp`.
+func (p *RetryPolicy) wait(tried int, resp *http.Response) time.Duration {
+	if p.MaxRetryAfter >= 0 {
+		if wait, ok := parseRetryAfter(resp, time.Now()); ok {
+			max := p.MaxRetryAfter
+			if max == 0 {
+				max = DefaultMaxRetryAfter
+			}
+			if wait > max {
+				wait = max
+			}
+			return wait
+		}
+	}
+	return p.backoff(tried)
+}
+
+// RetryPolicy sets the retry condition and backoff strategy of the client.
+// The policy is copied, so changing it afterwards does not affect the client.
+//
+// Usage:
+//    policy := gohttp.DefaultRetryPolicy()
+//    policy.MaxAttempts = 5
+//    gohttp.New().RetryPolicy(policy).Get(url)
+func (c *Client) RetryPolicy(policy *RetryPolicy) *Client {
+	if policy != nil {
+		p := *policy
+		c.retryPolicy = &p
+	}
+	return c
+}
+
+// getRetryPolicy returns the policy in effect, `Retries` value is used when
+// no custom policy is given or its `MaxAttempts` is not set.
+func (c *Client) getRetryPolicy() *RetryPolicy {
+	p := RetryPolicy{}
+	if c.retryPolicy != nil {
+		p = *c.retryPolicy
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = c.retries
+	}
+	return &p
+}
+
+// discardResponse drains and closes response body, so that the underlying
+// connection can be reused by next attempt.
+func discardResponse(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	io.CopyN(ioutil.Discard, resp.Body, 4096)
+	resp.Body.Close()
+}
@@ -0,0 +1,138 @@
+package gohttp_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+func TestRetryPolicyStatusCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	tried := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tried++
+		if tried < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	policy := &gohttp.RetryPolicy{
+		MaxAttempts:     5,
+		StatusCodes:     []int{http.StatusServiceUnavailable},
+		InitialInterval: 10 * time.Millisecond,
+	}
+	resp, err := gohttp.New().RetryPolicy(policy).Get(ts.URL)
+	assert.NoError(err, "request should succeed after retries")
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(3, tried, "should stop retrying once request succeeds")
+}
+
+func TestRetryPolicyMaxAttempts(t *testing.T) {
+	assert := assert.New(t)
+
+	tried := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tried++
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	policy := gohttp.DefaultRetryPolicy()
+	policy.InitialInterval = time.Millisecond
+	resp, err := gohttp.New().RetryPolicy(policy).Get(ts.URL)
+	assert.NoError(err, "status code failure should not be returned as error")
+	assert.Equal(http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(3, tried)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	var times []time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	policy := &gohttp.RetryPolicy{
+		MaxAttempts:     3,
+		StatusCodes:     []int{http.StatusServiceUnavailable},
+		InitialInterval: 50 * time.Millisecond,
+		Multiplier:      2,
+	}
+	gohttp.New().RetryPolicy(policy).Get(ts.URL)
+	assert.Len(times, 3)
+	assert.True(times[1].Sub(times[0]) >= 50*time.Millisecond, "first retry should wait initial interval")
+	assert.True(times[2].Sub(times[1]) >= 100*time.Millisecond, "second retry should wait twice as long")
+}
+
+func TestRetryPolicyMaxElapsedTime(t *testing.T) {
+	assert := assert.New(t)
+
+	tried := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tried++
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	policy := &gohttp.RetryPolicy{
+		MaxAttempts:     10,
+		StatusCodes:     []int{http.StatusServiceUnavailable},
+		InitialInterval: 100 * time.Millisecond,
+		MaxElapsedTime:  150 * time.Millisecond,
+	}
+	gohttp.New().RetryPolicy(policy).Get(ts.URL)
+	assert.Equal(2, tried, "should give up when next wait exceeds max elapsed time")
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	tried := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tried++
+		w.Header().Set("X-Attempt-Status", "pending")
+	}))
+	defer ts.Close()
+
+	var lastErr error
+	policy := &gohttp.RetryPolicy{
+		MaxAttempts: 4,
+		ShouldRetry: func(resp *http.Response, err error) bool {
+			lastErr = err
+			return err == nil && resp.Header.Get("X-Attempt-Status") == "pending"
+		},
+	}
+	gohttp.New().RetryPolicy(policy).Get(ts.URL)
+	assert.Equal(4, tried)
+	assert.NoError(lastErr)
+}
+
+func TestRetryPolicyNotRetryOnSuccess(t *testing.T) {
+	assert := assert.New(t)
+
+	tried := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tried++
+	}))
+	defer ts.Close()
+
+	policy := gohttp.DefaultRetryPolicy()
+	policy.ShouldRetry = func(resp *http.Response, err error) bool {
+		return errors.Is(err, http.ErrHandlerTimeout)
+	}
+	gohttp.New().RetryPolicy(policy).Get(ts.URL)
+	assert.Equal(1, tried)
+}
@@ -0,0 +1,214 @@
+package gohttp
+
+import (
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session is a cookie jar which keeps cookies set by servers, and sends them back
+// on later requests, just like a browser does.
+// It implements `http.CookieJar`, and is safe for concurrent use.
+//
+// Cookies in a session can be saved to and loaded from file, see `Save` and `Load`.
+type Session struct {
+	jar *cookiejar.Jar
+
+	// `cookiejar.Jar` does not expose stored cookies, so the session keeps its own
+	// record of every cookie with full attributes, to be able to save them.
+	mu      sync.Mutex
+	entries map[string]*cookieEntry
+}
+
+// cookieEntry is a cookie with attributes resolved against the url it is set from.
+type cookieEntry struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	HostOnly bool      `json:"host_only"`
+	Path     string    `json:"path"`
+	Secure   bool      `json:"secure"`
+	HttpOnly bool      `json:"http_only"`
+	Expires  time.Time `json:"expires"`
+}
+
+func (e *cookieEntry) key() string {
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+func (e *cookieEntry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !e.Expires.After(now)
+}
+
+// NewSession returns an empty session.
+//
+// NOTE: public suffix list is not used, so servers can set cookies for domains like `co.uk`.
+func NewSession() *Session {
+	// error is always nil when options is nil
+	jar, _ := cookiejar.New(nil)
+	return &Session{
+		jar:     jar,
+		entries: make(map[string]*cookieEntry),
+	}
+}
+
+// SetCookies stores cookies received from a response of url `u`.
+func (s *Session) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	s.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cookie := range cookies {
+		entry, ok := newCookieEntry(u, cookie, now)
+		if !ok {
+			continue
+		}
+		if entry.expired(now) {
+			delete(s.entries, entry.key())
+		} else {
+			s.entries[entry.key()] = entry
+		}
+	}
+}
+
+// Cookies returns cookies that should be sent in a request to url `u`.
+func (s *Session) Cookies(u *url.URL) []*http.Cookie {
+	return s.jar.Cookies(u)
+}
+
+// cookieEntries returns all unexpired cookies in the session, sorted by domain, path and name.
+func (s *Session) cookieEntries() []*cookieEntry {
+	now := time.Now()
+	s.mu.Lock()
+	entries := make([]*cookieEntry, 0, len(s.entries))
+	for key, entry := range s.entries {
+		if entry.expired(now) {
+			delete(s.entries, key)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key() < entries[j].key()
+	})
+	return entries
+}
+
+// addCookieEntry stores a cookie loaded from file, as if it is set by a response from its domain.
+func (s *Session) addCookieEntry(entry *cookieEntry) {
+	if entry.Name == "" || entry.Domain == "" || entry.expired(time.Now()) {
+		return
+	}
+
+	u := &url.URL{Scheme: "http", Host: entry.Domain, Path: entry.Path}
+	if entry.Secure {
+		u.Scheme = "https"
+	}
+	cookie := &http.Cookie{
+		Name:     entry.Name,
+		Value:    entry.Value,
+		Path:     entry.Path,
+		Expires:  entry.Expires,
+		Secure:   entry.Secure,
+		HttpOnly: entry.HttpOnly,
+	}
+	if !entry.HostOnly {
+		cookie.Domain = entry.Domain
+	}
+	s.SetCookies(u, []*http.Cookie{cookie})
+}
+
+// newCookieEntry resolves cookie attributes the same way as a cookie jar, see RFC 6265 section 5.3.
+// false is returned if the cookie would be rejected.
+func newCookieEntry(u *url.URL, cookie *http.Cookie, now time.Time) (*cookieEntry, bool) {
+	if cookie.Name == "" {
+		return nil, false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	entry := &cookieEntry{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Domain:   host,
+		HostOnly: true,
+		Path:     cookie.Path,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+	}
+
+	if cookie.Domain != "" {
+		domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+		if domain != host {
+			if net.ParseIP(host) != nil || !strings.HasSuffix(host, "."+domain) {
+				return nil, false
+			}
+			entry.Domain = domain
+			entry.HostOnly = false
+		} else if net.ParseIP(host) == nil {
+			entry.HostOnly = false
+		}
+	}
+
+	if entry.Path == "" || entry.Path[0] != '/' {
+		entry.Path = defaultCookiePath(u.Path)
+	}
+
+	switch {
+	case cookie.MaxAge < 0:
+		entry.Expires = time.Unix(1, 0)
+	case cookie.MaxAge > 0:
+		entry.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+	case !cookie.Expires.IsZero():
+		entry.Expires = cookie.Expires
+	}
+	return entry, true
+}
+
+// defaultCookiePath returns the directory part of url path, see RFC 6265 section 5.1.4.
+func defaultCookiePath(path string) string {
+	if path == "" || path[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(path, "/")
+	if i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// CookieJar sets the cookie jar of the client, cookies set by responses are saved in it, and
+// sent on later requests. Clients cloned with `New` share the same cookie jar.
+//
+// Usage:
+//    c := gohttp.New().CookieJar(gohttp.NewSession())
+//    c.New().Form(login).Post("https://somesite.com/login")
+//    c.New().Get("https://somesite.com/profile")  // logged in
+func (c *Client) CookieJar(jar http.CookieJar) *Client {
+	c.jar = jar
+	return c
+}
+
+// Cookies returns cookies in the cookie jar that will be sent to the url.
+// If url is empty, the base url set by `URL` is used.
+// nil is returned if no cookie jar is set.
+func (c *Client) Cookies(rawurl string) []*http.Cookie {
+	if c.jar == nil {
+		return nil
+	}
+	if rawurl == "" {
+		rawurl = c.url
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil
+	}
+	return c.jar.Cookies(u)
+}
@@ -0,0 +1,84 @@
+package gohttp_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// newLoginServer returns a server which sets a session cookie on `/login`,
+// and tells whether the request is logged in on other paths.
+func newLoginServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "cizixs", Path: "/"})
+			return
+		}
+		cookie, err := r.Cookie("session")
+		if err != nil {
+			fmt.Fprint(w, "anonymous")
+			return
+		}
+		fmt.Fprintf(w, "hello, %s", cookie.Value)
+	}))
+}
+
+func TestSessionKeepsCookies(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newLoginServer()
+	defer ts.Close()
+
+	c := gohttp.New().URL(ts.URL).CookieJar(gohttp.NewSession())
+	_, err := c.New().Path("/login").Post()
+	assert.NoError(err)
+
+	resp, err := c.New().Path("/profile").Get()
+	assert.NoError(err)
+	data, _ := resp.AsString()
+	assert.Equal("hello, cizixs", data, "cloned clients should share cookies")
+
+	cookies := c.Cookies("")
+	if assert.Len(cookies, 1) {
+		assert.Equal("session", cookies[0].Name)
+		assert.Equal("cizixs", cookies[0].Value)
+	}
+}
+
+func TestNoSessionDropsCookies(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newLoginServer()
+	defer ts.Close()
+
+	c := gohttp.New().URL(ts.URL)
+	c.New().Path("/login").Post()
+
+	resp, err := c.New().Path("/profile").Get()
+	assert.NoError(err)
+	data, _ := resp.AsString()
+	assert.Equal("anonymous", data)
+	assert.Nil(c.Cookies(ts.URL))
+}
+
+func TestSessionWithStaticCookie(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "visited", Value: "yes"})
+		fmt.Fprintf(w, "%d", len(r.Cookies()))
+	}))
+	defer ts.Close()
+
+	c := gohttp.New().URL(ts.URL).CookieJar(gohttp.NewSession())
+	c.New().Get()
+
+	resp, _ := c.New().Cookie(&http.Cookie{Name: "lang", Value: "en"}).Get()
+	data, _ := resp.AsString()
+	assert.Equal("2", data, "both static and session cookies should be sent")
+}
@@ -0,0 +1,202 @@
+package gohttp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSignatureHeader is the header `HMACSigner` puts signature in, if `Header` is empty.
+const DefaultSignatureHeader = "X-Signature"
+
+// Signer signs a request right before it is sent, after url, headers and body are final
+// and hooks have run. It is called again for every retry attempt, so that timestamps and
+// nonces are fresh. Request body can be read with `req.GetBody`.
+type Signer interface {
+	Sign(req *http.Request) error
+}
+
+// SignerFunc is an adapter to allow the use of ordinary functions as `Signer`.
+type SignerFunc func(req *http.Request) error
+
+// Sign calls f(req).
+func (f SignerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
+
+// Signer sets the signer of requests, it replaces the previous one, including `AWSSigner`.
+//
+// Usage:
+//    signer := &gohttp.HMACSigner{
+//        KeyID:           "partner-1",
+//        Key:             []byte("secret"),
+//        Headers:         []string{"Content-Type"},
+//        TimestampHeader: "X-Timestamp",
+//        NonceHeader:     "X-Nonce",
+//        Format:          "HMAC-SHA256 keyId={key_id}, signature={signature}",
+//        Header:          "Authorization",
+//    }
+//    gohttp.New().Signer(signer).JSON(`{"amount":100}`).Post("https://partner.example.com/payments")
+func (c *Client) Signer(signer Signer) *Client {
+	c.signer = signer
+	return c
+}
+
+// HMACSigner signs requests with HMAC over a canonical string of the request.
+//
+// By default the canonical string is built by `CanonicalRequest`, the signature is hex encoded
+// and put in `X-Signature` header. Every part can be changed to match what the server expects.
+type HMACSigner struct {
+	// KeyID identifies the key, it is available as `{key_id}` in `Format`
+	KeyID string
+
+	// Key is the shared secret
+	Key []byte
+
+	// Hash is the hash function of HMAC and body hash, nil means SHA-256
+	Hash func() hash.Hash
+
+	// Headers are the request headers included in the signature, in the given order.
+	// Timestamp and nonce headers are always included.
+	Headers []string
+
+	// TimestampHeader is set to the signing time, empty means no timestamp is sent
+	TimestampHeader string
+
+	// TimestampFormat is the layout of timestamp, empty means unix seconds
+	TimestampFormat string
+
+	// NonceHeader is set to a random nonce, empty means no nonce is sent
+	NonceHeader string
+
+	// Header is the header signature is set to, empty means `X-Signature`
+	Header string
+
+	// Format is the header value, with placeholders `{key_id}`, `{signature}`, `{headers}`
+	// (signed header names separated by space), `{timestamp}` and `{nonce}`.
+	// Empty means the signature only.
+	Format string
+
+	// Encode encodes the signature, nil means hex, `base64.StdEncoding.EncodeToString` is another choice
+	Encode func([]byte) string
+
+	// Canonicalize builds the string to sign, nil means `CanonicalRequest`
+	Canonicalize func(req *http.Request, headers []string, bodyHash string) string
+
+	// Now returns the signing time, nil means `time.Now`
+	Now func() time.Time
+}
+
+// CanonicalRequest is the default string to sign of `HMACSigner`, lines joined by `\n`:
+//    METHOD
+//    /escaped/path
+//    sorted=query&string=encoded
+//    header-name:trimmed value     (one line for each signed header, in order)
+//    hex encoded body hash
+func CanonicalRequest(req *http.Request, headers []string, bodyHash string) string {
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	lines := []string{req.Method, path, canonicalQuery(req.URL.Query())}
+	for _, name := range headers {
+		value := strings.Join(strings.Fields(strings.Join(req.Header.Values(name), ",")), " ")
+		lines = append(lines, strings.ToLower(name)+":"+value)
+	}
+	lines = append(lines, bodyHash)
+	return strings.Join(lines, "\n")
+}
+
+// Sign sets timestamp, nonce and signature headers of the request.
+func (s *HMACSigner) Sign(req *http.Request) error {
+	if len(s.Key) == 0 {
+		return errors.New("gohttp: hmac signer has no key")
+	}
+	newHash := s.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+
+	headers := append([]string(nil), s.Headers...)
+	timestamp, nonce := "", ""
+	if s.TimestampHeader != "" {
+		now := time.Now()
+		if s.Now != nil {
+			now = s.Now()
+		}
+		timestamp = strconv.FormatInt(now.Unix(), 10)
+		if s.TimestampFormat != "" {
+			timestamp = now.UTC().Format(s.TimestampFormat)
+		}
+		req.Header.Set(s.TimestampHeader, timestamp)
+		headers = appendHeaderName(headers, s.TimestampHeader)
+	}
+	if s.NonceHeader != "" {
+		var err error
+		if nonce, err = randomString(16); err != nil {
+			return err
+		}
+		req.Header.Set(s.NonceHeader, nonce)
+		headers = appendHeaderName(headers, s.NonceHeader)
+	}
+
+	body, err := requestBody(req)
+	if err != nil {
+		return err
+	}
+	h := newHash()
+	h.Write(body)
+	bodyHash := hex.EncodeToString(h.Sum(nil))
+
+	canonicalize := s.Canonicalize
+	if canonicalize == nil {
+		canonicalize = CanonicalRequest
+	}
+	mac := hmac.New(newHash, s.Key)
+	mac.Write([]byte(canonicalize(req, headers, bodyHash)))
+
+	encode := s.Encode
+	if encode == nil {
+		encode = hex.EncodeToString
+	}
+	signature := encode(mac.Sum(nil))
+
+	names := make([]string, len(headers))
+	for i, name := range headers {
+		names[i] = strings.ToLower(name)
+	}
+	value := signature
+	if s.Format != "" {
+		value = strings.NewReplacer(
+			"{key_id}", s.KeyID,
+			"{signature}", signature,
+			"{headers}", strings.Join(names, " "),
+			"{timestamp}", timestamp,
+			"{nonce}", nonce,
+		).Replace(s.Format)
+	}
+
+	header := s.Header
+	if header == "" {
+		header = DefaultSignatureHeader
+	}
+	req.Header.Set(header, value)
+	return nil
+}
+
+// appendHeaderName appends name to headers, unless it is already there.
+func appendHeaderName(headers []string, name string) []string {
+	for _, h := range headers {
+		if strings.EqualFold(h, name) {
+			return headers
+		}
+	}
+	return append(headers, name)
+}
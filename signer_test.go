@@ -0,0 +1,140 @@
+package gohttp_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// newHMACServer verifies signature of `HMACSigner` with default canonicalization,
+// signed headers are `Content-Type` and `X-Timestamp`.
+func newHMACServer(key string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodyHash := sha256.Sum256(body)
+		canonical := strings.Join([]string{
+			r.Method,
+			r.URL.EscapedPath(),
+			r.URL.Query().Encode(),
+			"content-type:" + r.Header.Get("Content-Type"),
+			"x-timestamp:" + r.Header.Get("X-Timestamp"),
+			hex.EncodeToString(bodyHash[:]),
+		}, "\n")
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(canonical))
+		expected := "keyId=partner-1,headers=content-type x-timestamp,signature=" + hex.EncodeToString(mac.Sum(nil))
+
+		if r.Header.Get("Authorization") != expected {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		w.Write(body)
+	}))
+}
+
+func TestHMACSigner(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newHMACServer("secret")
+	defer ts.Close()
+
+	signer := &gohttp.HMACSigner{
+		KeyID:           "partner-1",
+		Key:             []byte("secret"),
+		Headers:         []string{"Content-Type"},
+		TimestampHeader: "X-Timestamp",
+		Header:          "Authorization",
+		Format:          "keyId={key_id},headers={headers},signature={signature}",
+	}
+	c := gohttp.New().URL(ts.URL).Signer(signer)
+
+	resp, err := c.New().Path("/payments").Query("b", "2").Query("a", "1").JSON(`{"amount":100}`).Post()
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	data, _ := resp.AsString()
+	assert.Equal(`{"amount":100}`, data)
+
+	resp, err = c.New().Path("/payments").Get()
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	signer.Key = []byte("wrong")
+	resp, err = c.New().Get()
+	assert.NoError(err)
+	assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHMACSignerCustom(t *testing.T) {
+	assert := assert.New(t)
+
+	var headers http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+	}))
+	defer ts.Close()
+
+	signer := &gohttp.HMACSigner{
+		Key:             []byte("secret"),
+		Hash:            sha1.New,
+		TimestampHeader: "Date",
+		TimestampFormat: http.TimeFormat,
+		NonceHeader:     "X-Nonce",
+		Encode:          base64.StdEncoding.EncodeToString,
+		Canonicalize: func(req *http.Request, headers []string, bodyHash string) string {
+			return req.Method + " " + req.URL.Path + " " + strings.Join(headers, ",") + " " + req.Header.Get("Date")
+		},
+		Now: func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+	}
+	_, err := gohttp.New().Signer(signer).Get(ts.URL + "/orders")
+	assert.NoError(err)
+
+	mac := hmac.New(sha1.New, []byte("secret"))
+	mac.Write([]byte("GET /orders Date,X-Nonce Tue, 02 Jan 2024 03:04:05 GMT"))
+	assert.Equal("Tue, 02 Jan 2024 03:04:05 GMT", headers.Get("Date"))
+	assert.NotEmpty(headers.Get("X-Nonce"))
+	assert.Equal(base64.StdEncoding.EncodeToString(mac.Sum(nil)), headers.Get(gohttp.DefaultSignatureHeader))
+}
+
+func TestSignerFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.Header.Get("X-Attempt-Signed") != "yes" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	signed := 0
+	signer := gohttp.SignerFunc(func(req *http.Request) error {
+		signed++
+		req.Header.Set("X-Attempt-Signed", "yes")
+		return nil
+	})
+	resp, err := gohttp.New().Signer(signer).Get(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(1, signed)
+
+	// signing error stops the request
+	_, err = gohttp.New().Signer(gohttp.SignerFunc(func(*http.Request) error {
+		return errors.New("key expired")
+	})).Get(ts.URL)
+	assert.Error(err)
+	assert.Equal(1, attempts)
+}
@@ -0,0 +1,225 @@
+package gohttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SOCKS5 protocol values, see RFC 1928 and RFC 1929.
+const (
+	socksVersion        = 0x05
+	socksAuthNone       = 0x00
+	socksAuthPassword   = 0x02
+	socksNoAcceptable   = 0xff
+	socksPasswordAuth   = 0x01
+	socksCmdConnect     = 0x01
+	socksAddrIPv4       = 0x01
+	socksAddrDomain     = 0x03
+	socksAddrIPv6       = 0x04
+	socksReplySucceeded = 0x00
+)
+
+// socksReplies are the messages of SOCKS5 reply codes.
+var socksReplies = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// socksDialer connects to addresses through a SOCKS5 proxy.
+//
+// With `socks5://` proxy urls, target host names are resolved locally and the proxy gets IP addresses,
+// with `socks5h://` the proxy gets host names and resolves them, which is necessary when names are only
+// known on the proxy side, like SSH tunnels into private networks.
+type socksDialer struct {
+	proxyAddr string
+	username  string
+	password  string
+	remoteDNS bool
+
+	// forward connects to the proxy server
+	forward dialFunc
+}
+
+func newSOCKSDialer(proxy *url.URL, forward dialFunc) *socksDialer {
+	d := &socksDialer{
+		proxyAddr: canonicalAddr(proxy),
+		remoteDNS: strings.EqualFold(proxy.Scheme, "socks5h"),
+		forward:   forward,
+	}
+	if proxy.User != nil {
+		d.username = proxy.User.Username()
+		d.password, _ = proxy.User.Password()
+	}
+	return d
+}
+
+// DialContext connects to addr through the proxy.
+func (d *socksDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, fmt.Errorf("gohttp: socks5 proxy does not support network %s", network)
+	}
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 0xffff {
+		return nil, fmt.Errorf("gohttp: invalid port in address %s", addr)
+	}
+	if !d.remoteDNS && net.ParseIP(host) == nil {
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		host = ips[0].IP.String()
+	}
+
+	conn, err := d.forward(ctx, "tcp", d.proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// the handshake is bound to context, deadline and cancellation interrupt it
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	cancelled := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+			close(cancelled)
+		case <-done:
+		}
+	}()
+
+	err = d.handshake(conn, host, port)
+	close(done)
+	select {
+	case <-cancelled:
+		err = ctx.Err()
+	default:
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// handshake negotiates authentication and asks the proxy to connect to host:port.
+func (d *socksDialer) handshake(conn net.Conn, host string, port int) error {
+	methods := []byte{socksAuthNone}
+	if d.username != "" {
+		methods = append(methods, socksAuthPassword)
+	}
+	if _, err := conn.Write(append([]byte{socksVersion, byte(len(methods))}, methods...)); err != nil {
+		return fmt.Errorf("gohttp: socks5 handshake: %v", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("gohttp: socks5 handshake: %v", err)
+	}
+	if reply[0] != socksVersion {
+		return fmt.Errorf("gohttp: unexpected socks version %d", reply[0])
+	}
+
+	switch reply[1] {
+	case socksAuthNone:
+	case socksAuthPassword:
+		if err := d.authenticate(conn); err != nil {
+			return err
+		}
+	case socksNoAcceptable:
+		return errors.New("gohttp: socks5 proxy accepts none of the authentication methods")
+	default:
+		return fmt.Errorf("gohttp: unsupported socks5 authentication method %d", reply[1])
+	}
+
+	req := []byte{socksVersion, socksCmdConnect, 0}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(append(req, socksAddrIPv4), ip4...)
+		} else {
+			req = append(append(req, socksAddrIPv6), ip...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("gohttp: host name too long for socks5: %s", host)
+		}
+		req = append(append(req, socksAddrDomain, byte(len(host))), host...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("gohttp: socks5 connect: %v", err)
+	}
+
+	// reply is VER REP RSV ATYP BND.ADDR BND.PORT, the bound address is not used
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("gohttp: socks5 connect: %v", err)
+	}
+	if header[1] != socksReplySucceeded {
+		msg, ok := socksReplies[header[1]]
+		if !ok {
+			msg = fmt.Sprintf("unknown reply code %d", header[1])
+		}
+		return fmt.Errorf("gohttp: socks5 proxy failed to connect to %s: %s", net.JoinHostPort(host, strconv.Itoa(port)), msg)
+	}
+	var addrLen int
+	switch header[3] {
+	case socksAddrIPv4:
+		addrLen = net.IPv4len
+	case socksAddrIPv6:
+		addrLen = net.IPv6len
+	case socksAddrDomain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return fmt.Errorf("gohttp: socks5 connect: %v", err)
+		}
+		addrLen = int(size[0])
+	default:
+		return fmt.Errorf("gohttp: unknown socks5 address type %d", header[3])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, addrLen+2)); err != nil {
+		return fmt.Errorf("gohttp: socks5 connect: %v", err)
+	}
+	return nil
+}
+
+// authenticate sends username and password, see RFC 1929.
+func (d *socksDialer) authenticate(conn net.Conn) error {
+	if len(d.username) > 255 || len(d.password) > 255 {
+		return errors.New("gohttp: socks5 username or password too long")
+	}
+	req := []byte{socksPasswordAuth, byte(len(d.username))}
+	req = append(req, d.username...)
+	req = append(req, byte(len(d.password)))
+	req = append(req, d.password...)
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("gohttp: socks5 authentication: %v", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("gohttp: socks5 authentication: %v", err)
+	}
+	if reply[1] != 0 {
+		return errors.New("gohttp: socks5 authentication failed")
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package gohttp_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// socksServer is a minimal SOCKS5 proxy for tests, it records the addresses clients ask for.
+type socksServer struct {
+	ln       net.Listener
+	username string
+	password string
+	targets  chan string
+}
+
+func newSOCKSServer(t *testing.T, username, password string) *socksServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &socksServer{ln: ln, username: username, password: password, targets: make(chan string, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *socksServer) Close() { s.ln.Close() }
+
+func (s *socksServer) URL(scheme string) string {
+	if s.username != "" {
+		return fmt.Sprintf("%s://%s:%s@%s", scheme, s.username, s.password, s.ln.Addr())
+	}
+	return fmt.Sprintf("%s://%s", scheme, s.ln.Addr())
+}
+
+func (s *socksServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+	methods := make([]byte, buf[1])
+	io.ReadFull(conn, methods)
+	if s.username == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+		io.ReadFull(conn, buf)
+		user := make([]byte, buf[1])
+		io.ReadFull(conn, user)
+		io.ReadFull(conn, buf[:1])
+		pass := make([]byte, buf[0])
+		io.ReadFull(conn, pass)
+		if string(user) != s.username || string(pass) != s.password {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	var host string
+	switch header[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 3:
+		io.ReadFull(conn, buf[:1])
+		name := make([]byte, buf[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	}
+	io.ReadFull(conn, buf)
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf))))
+	s.targets <- addr
+
+	target, err := net.Dial("tcp", addr)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func TestSOCKSProxy(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	target := "http://localhost:" + port
+
+	proxy := newSOCKSServer(t, "", "")
+	defer proxy.Close()
+
+	// remote DNS: the proxy gets the host name
+	resp, err := gohttp.New().Proxy(proxy.URL("socks5h")).Get(target)
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("localhost:"+port, data)
+		assert.Equal("localhost:"+port, <-proxy.targets)
+	}
+
+	// local DNS: the proxy gets the IP address
+	resp, err = gohttp.New().Proxy(proxy.URL("socks5")).Get(target)
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("localhost:"+port, data)
+		assert.Equal("127.0.0.1:"+port, <-proxy.targets)
+	}
+
+	// the proxy is not used by the client a proxied one is cloned from
+	c := gohttp.New()
+	_, err = c.New().Proxy(proxy.URL("socks5h")).Get(target)
+	assert.NoError(err)
+	<-proxy.targets
+	_, err = c.Get(target)
+	assert.NoError(err)
+	assert.Len(proxy.targets, 0)
+}
+
+func TestSOCKSProxyAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	proxy := newSOCKSServer(t, "tunnel", "s3cret")
+	defer proxy.Close()
+
+	resp, err := gohttp.New().Proxy(proxy.URL("socks5")).Get(ts.URL)
+	if assert.NoError(err) {
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	}
+
+	_, err = gohttp.New().Proxy("socks5://tunnel:wrong@" + proxy.ln.Addr().String()).Get(ts.URL)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "socks5 authentication failed")
+	}
+}
@@ -0,0 +1,269 @@
+package gohttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// tlsSettings are TLS options of a client. It is shared by cloned clients, and replaced
+// by a modified copy when any option changes, so that clients with the same options share
+// the transport and its connections, while options of one client never leak to others.
+type tlsSettings struct {
+	certFile           string
+	keyFile            string
+	certFunc           func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+	rootCAs            []string
+	minVersion         uint16
+	cipherSuites       []uint16
+	insecureSkipVerify bool
+	pins               map[string][]string
+	pinReportOnly      bool
+
+	// transports are copies of base transports with TLS config, they are built on first use
+	mu         sync.Mutex
+	transports map[tlsTransportKey]*http.Transport
+}
+
+// tlsTransportKey identifies a transport built from the settings. Pin mismatches in report-only
+// mode are logged by the logger of the client, so clients with different loggers get different transports.
+type tlsTransportKey struct {
+	base   *http.Transport
+	logger *log.Logger
+}
+
+// tlsOptions returns a copy of TLS settings for the client to modify.
+func (c *Client) tlsOptions() *tlsSettings {
+	s := &tlsSettings{pins: make(map[string][]string)}
+	if c.tls != nil {
+		s.certFile = c.tls.certFile
+		s.keyFile = c.tls.keyFile
+		s.certFunc = c.tls.certFunc
+		s.rootCAs = append([]string(nil), c.tls.rootCAs...)
+		s.minVersion = c.tls.minVersion
+		s.cipherSuites = append([]uint16(nil), c.tls.cipherSuites...)
+		s.insecureSkipVerify = c.tls.insecureSkipVerify
+		for host, pins := range c.tls.pins {
+			s.pins[host] = append([]string(nil), pins...)
+		}
+		s.pinReportOnly = c.tls.pinReportOnly
+	}
+	c.tls = s
+	return s
+}
+
+// ClientCert sets the client certificate and private key presented to servers that
+// require mutual TLS. Both files are PEM encoded, `certFile` can contain intermediate
+// certificates after the leaf one. Files are loaded when the first request is sent,
+// and errors are returned by the request.
+//
+// Files are watched for changes: when they are replaced, for example by certificate rotation,
+// the new certificate is presented on new connections, while in-flight requests are not affected.
+// If the new files can not be loaded, like the key is not written yet, the previous certificate
+// is used until they can.
+//
+// TLS settings are applied to the default `*http.Transport`, or the one set by `Transport`,
+// other RoundTripper implementations are responsible for their own TLS config.
+//
+// Usage:
+//    gohttp.New().ClientCert("client.pem", "client-key.pem").RootCAs("internal-ca.pem").Get("https://internal.example.com")
+func (c *Client) ClientCert(certFile, keyFile string) *Client {
+	s := c.tlsOptions()
+	s.certFile = certFile
+	s.keyFile = keyFile
+	s.certFunc = nil
+	return c
+}
+
+// ClientCertFunc sets the function which provides client certificate when server requests one,
+// it is called for every new connection. It is useful when certificates come from somewhere
+// else than files, like a secret store or a hardware token. It replaces `ClientCert`.
+func (c *Client) ClientCertFunc(fn func(*tls.CertificateRequestInfo) (*tls.Certificate, error)) *Client {
+	s := c.tlsOptions()
+	s.certFile = ""
+	s.keyFile = ""
+	s.certFunc = fn
+	return c
+}
+
+// RootCAs sets the PEM encoded CA bundles used to verify server certificates, instead of
+// the system certificate pool. It can be called multiple times to add more files.
+func (c *Client) RootCAs(pemFiles ...string) *Client {
+	s := c.tlsOptions()
+	s.rootCAs = append(s.rootCAs, pemFiles...)
+	return c
+}
+
+// MinTLSVersion sets the minimum TLS version, like `tls.VersionTLS12`.
+func (c *Client) MinTLSVersion(version uint16) *Client {
+	c.tlsOptions().minVersion = version
+	return c
+}
+
+// CipherSuites limits the cipher suites of TLS 1.0-1.2 connections, like `tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256`.
+// TLS 1.3 cipher suites are not configurable.
+func (c *Client) CipherSuites(suites ...uint16) *Client {
+	c.tlsOptions().cipherSuites = append([]uint16(nil), suites...)
+	return c
+}
+
+// InsecureSkipVerify turns off verification of server certificate chain and host name.
+// This is only for development and testing purpose, connections are open to man-in-the-middle attacks.
+func (c *Client) InsecureSkipVerify(skip bool) *Client {
+	c.tlsOptions().insecureSkipVerify = skip
+	return c
+}
+
+// transportFor returns a copy of base transport with TLS config built from the settings.
+// The copy is reused by all requests with the same base transport and logger. `logger` logs pin
+// mismatches in report-only mode, nil means they are not logged.
+func (s *tlsSettings) transportFor(base *http.Transport, logger *log.Logger) (*http.Transport, error) {
+	if len(s.pins) == 0 || !s.pinReportOnly {
+		// logger is not used, clients with any logger share the transport
+		logger = nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := tlsTransportKey{base: base, logger: logger}
+	if t, ok := s.transports[key]; ok {
+		return t, nil
+	}
+	config, err := s.config(base.TLSClientConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	t := base.Clone()
+	t.TLSClientConfig = config
+	if s.transports == nil {
+		s.transports = make(map[tlsTransportKey]*http.Transport)
+	}
+	s.transports[key] = t
+	return t, nil
+}
+
+// config builds TLS config on top of the one of base transport, which can be nil.
+func (s *tlsSettings) config(base *tls.Config, logger *log.Logger) (*tls.Config, error) {
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	if s.certFunc != nil {
+		config.GetClientCertificate = s.certFunc
+	} else if s.certFile != "" || s.keyFile != "" {
+		reloader := &certReloader{certFile: s.certFile, keyFile: s.keyFile}
+		if err := reloader.reload(); err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = reloader.getCertificate
+	}
+
+	if len(s.rootCAs) > 0 {
+		pool := x509.NewCertPool()
+		for _, file := range s.rootCAs {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("gohttp: load root CAs: %v", err)
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("gohttp: no certificate found in %s", file)
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	if s.minVersion != 0 {
+		config.MinVersion = s.minVersion
+	}
+	if len(s.cipherSuites) > 0 {
+		config.CipherSuites = s.cipherSuites
+	}
+	if s.insecureSkipVerify {
+		config.InsecureSkipVerify = true
+	}
+	if len(s.pins) > 0 {
+		verify := config.VerifyConnection
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(cs); err != nil {
+					return err
+				}
+			}
+			return s.verifyPins(cs, logger)
+		}
+	}
+	return config, nil
+}
+
+// certReloader loads client certificate from files, and loads it again when files change.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certStat fileStat
+	keyStat  fileStat
+}
+
+// fileStat is what tells a file has changed.
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(name string) (fileStat, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return fileStat{}, err
+	}
+	return fileStat{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// reload loads certificate if files have changed since last load.
+func (r *certReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	certStat, err := statFile(r.certFile)
+	if err != nil {
+		return fmt.Errorf("gohttp: load client certificate: %v", err)
+	}
+	keyStat, err := statFile(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("gohttp: load client certificate: %v", err)
+	}
+	if r.cert != nil && certStat == r.certStat && keyStat == r.keyStat {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("gohttp: load client certificate: %v", err)
+	}
+	r.cert = &cert
+	r.certStat = certStat
+	r.keyStat = keyStat
+	return nil
+}
+
+// getCertificate is used as `tls.Config.GetClientCertificate`.
+func (r *certReloader) getCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	// keep using the previous certificate if files are being replaced
+	reloadErr := r.reload()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert == nil {
+		return nil, reloadErr
+	}
+	return r.cert, nil
+}
@@ -0,0 +1,244 @@
+package gohttp_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// testCA issues certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA() *testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gohttp test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue writes a client certificate and its key to `dir/name.pem` and `dir/name-key.pem`.
+func (ca *testCA) issue(t *testing.T, dir, name string) (string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, file, typ string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newMTLSServer returns a TLS server which requires client certificates issued by ca,
+// and echoes the common name of client certificate.
+func newMTLSServer(ca *testCA) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: ca.pool}
+	ts.StartTLS()
+	return ts
+}
+
+// serverCAFile writes the certificate of test server to a file, to be used as root CA.
+func serverCAFile(t *testing.T, dir string, ts *httptest.Server) string {
+	file := filepath.Join(dir, "server-ca.pem")
+	writePEM(t, file, "CERTIFICATE", ts.Certificate().Raw)
+	return file
+}
+
+func TestClientCert(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ca := newTestCA()
+	ts := newMTLSServer(ca)
+	defer ts.Close()
+
+	certFile, keyFile := ca.issue(t, dir, "orders-service")
+	c := gohttp.New().URL(ts.URL).RootCAs(serverCAFile(t, dir, ts))
+
+	_, err := c.New().Get()
+	assert.Error(err, "server requires client certificate")
+
+	resp, err := c.New().ClientCert(certFile, keyFile).Get()
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("orders-service", data)
+	}
+
+	_, err = c.New().ClientCert(filepath.Join(dir, "missing.pem"), keyFile).Get()
+	assert.Error(err, "missing certificate file should be reported")
+}
+
+func TestRootCAs(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	base := gohttp.New().URL(ts.URL)
+	_, err := base.New().Get()
+	assert.Error(err, "test server certificate is not trusted by system")
+
+	resp, err := base.New().RootCAs(serverCAFile(t, dir, ts)).Get()
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err = base.New().InsecureSkipVerify(true).Get()
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	// options of a clone do not leak to the base client
+	_, err = base.Get()
+	assert.Error(err)
+
+	other := filepath.Join(dir, "other.pem")
+	ioutil.WriteFile(other, []byte("not a certificate"), 0600)
+	_, err = base.New().RootCAs(other).Get()
+	assert.Error(err)
+}
+
+func TestTLSVersionAndCiphers(t *testing.T) {
+	assert := assert.New(t)
+
+	var version, cipher uint16
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, cipher = r.TLS.Version, r.TLS.CipherSuite
+	}))
+	ts.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+
+	c := gohttp.New().URL(ts.URL).InsecureSkipVerify(true)
+	_, err := c.New().MinTLSVersion(tls.VersionTLS13).Get()
+	assert.Error(err, "server does not support TLS 1.3")
+
+	_, err = c.New().CipherSuites(tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384).Get()
+	assert.NoError(err)
+	assert.Equal(uint16(tls.VersionTLS12), version)
+	assert.Equal(tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, cipher)
+}
+
+func TestClientCertReload(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ca := newTestCA()
+	ts := newMTLSServer(ca)
+	defer ts.Close()
+	// every request uses a new connection, so that certificate is presented again
+	ts.Config.SetKeepAlivesEnabled(false)
+
+	certFile, keyFile := ca.issue(t, dir, "client")
+	c := gohttp.New().URL(ts.URL).RootCAs(serverCAFile(t, dir, ts)).ClientCert(certFile, keyFile)
+	resp, err := c.New().Get()
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("client", data)
+	}
+
+	// rotate certificate in place, the same client presents the new one
+	newCert, newKey := ca.issue(t, dir, "client-rotated")
+	for _, pair := range [][2]string{{newCert, certFile}, {newKey, keyFile}} {
+		data, _ := ioutil.ReadFile(pair[0])
+		ioutil.WriteFile(pair[1], data, 0600)
+		future := time.Now().Add(time.Minute)
+		os.Chtimes(pair[1], future, future)
+	}
+	resp, err = c.New().Get()
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("client-rotated", data)
+	}
+
+	// broken files do not break the client, previous certificate is still used
+	ioutil.WriteFile(keyFile, []byte("half written"), 0600)
+	resp, err = c.New().Get()
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("client-rotated", data)
+	}
+}
+
+func TestClientCertFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ca := newTestCA()
+	ts := newMTLSServer(ca)
+	defer ts.Close()
+
+	certFile, keyFile := ca.issue(t, dir, "from-vault")
+	calls := 0
+	provider := func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		calls++
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		return &cert, err
+	}
+
+	c := gohttp.New().URL(ts.URL).RootCAs(serverCAFile(t, dir, ts)).ClientCertFunc(provider)
+	for i := 0; i < 2; i++ {
+		resp, err := c.New().Get()
+		if assert.NoError(err) {
+			data, _ := resp.AsString()
+			assert.Equal("from-vault", data)
+		}
+	}
+	assert.Equal(1, calls, "connection is reused by the clones")
+}
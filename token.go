@@ -0,0 +1,128 @@
+package gohttp
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryDelta is how early a token is considered expired, so that it
+// does not expire on the way to server.
+const tokenExpiryDelta = 10 * time.Second
+
+// Token is an access token sent in `Authorization` header.
+type Token struct {
+	// AccessToken is the token that authorizes requests
+	AccessToken string
+
+	// TokenType is the type of token, empty value means "Bearer"
+	TokenType string
+
+	// RefreshToken is used to get a new access token when it expires, if the token source supports it
+	RefreshToken string
+
+	// Expiry is when the access token expires, zero value means it never expires
+	Expiry time.Time
+}
+
+// Valid reports whether the token is non-empty and not expired.
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.Expiry)
+}
+
+// authorization returns the `Authorization` header value of the token.
+func (t *Token) authorization() string {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+// TokenSource provides access tokens for requests.
+// `Token` is called before each request, so implementations usually fetch or refresh
+// tokens from authorization server. Tokens are cached by the client until they expire,
+// there is no need to do it again in the token source.
+type TokenSource interface {
+	Token() (*Token, error)
+}
+
+// TokenSourceFunc is an adapter to allow the use of ordinary functions as `TokenSource`.
+type TokenSourceFunc func() (*Token, error)
+
+// Token calls f()
+func (f TokenSourceFunc) Token() (*Token, error) {
+	return f()
+}
+
+// cachedTokenSource caches token of the underlying source until it expires.
+type cachedTokenSource struct {
+	mu    sync.Mutex
+	src   TokenSource
+	token *Token
+}
+
+// newCachedTokenSource wraps token source with cache, if it is not cached already.
+func newCachedTokenSource(src TokenSource) *cachedTokenSource {
+	if cached, ok := src.(*cachedTokenSource); ok {
+		return cached
+	}
+	return &cachedTokenSource{src: src}
+}
+
+func (s *cachedTokenSource) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+	token, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.AccessToken == "" {
+		return nil, errors.New("gohttp: token source returns empty token")
+	}
+	s.token = token
+	return token, nil
+}
+
+// invalidate drops cached token if it is still `token`, so that next call to `Token`
+// gets a new one from the underlying source.
+func (s *cachedTokenSource) invalidate(token *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == token {
+		s.token = nil
+	}
+}
+
+// BearerToken sets a static access token, which is sent in `Authorization: Bearer <token>` header.
+func (c *Client) BearerToken(token string) *Client {
+	return c.TokenSource(TokenSourceFunc(func() (*Token, error) {
+		return &Token{AccessToken: token}, nil
+	}))
+}
+
+// TokenSource sets where the client gets access tokens from. The token is cached until it expires,
+// and shared by clients cloned with `New`.
+//
+// If server answers 401 Unauthorized, the token is considered revoked: a new one is
+// requested from the source, and the request is sent once more with it.
+//
+// Usage:
+//    gohttp.New().TokenSource(gohttp.TokenSourceFunc(func() (*gohttp.Token, error) {
+//        return fetchTokenFromVault()
+//    })).Get(url)
+func (c *Client) TokenSource(src TokenSource) *Client {
+	if src != nil {
+		c.tokens = newCachedTokenSource(src)
+	}
+	return c
+}
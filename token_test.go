@@ -0,0 +1,145 @@
+package gohttp_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+func TestBearerToken(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	resp, err := gohttp.New().BearerToken("my-token").Get(ts.URL)
+	assert.NoError(err)
+	data, _ := resp.AsString()
+	assert.Equal("Bearer my-token", data)
+}
+
+func TestTokenSourceCache(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	fetched := 0
+	src := gohttp.TokenSourceFunc(func() (*gohttp.Token, error) {
+		fetched++
+		return &gohttp.Token{
+			AccessToken: fmt.Sprintf("token-%d", fetched),
+			Expiry:      time.Now().Add(time.Hour),
+		}, nil
+	})
+
+	c := gohttp.New().URL(ts.URL).TokenSource(src)
+	for i := 0; i < 3; i++ {
+		resp, err := c.New().Get()
+		assert.NoError(err)
+		data, _ := resp.AsString()
+		assert.Equal("Bearer token-1", data)
+	}
+	assert.Equal(1, fetched, "token should be cached until expiry, and shared by clones")
+}
+
+func TestTokenSourceExpiry(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	fetched := 0
+	src := gohttp.TokenSourceFunc(func() (*gohttp.Token, error) {
+		fetched++
+		return &gohttp.Token{
+			AccessToken: fmt.Sprintf("token-%d", fetched),
+			TokenType:   "bearer",
+			// expires within the safety margin, so it is refreshed every time
+			Expiry: time.Now().Add(time.Second),
+		}, nil
+	})
+
+	c := gohttp.New().URL(ts.URL).TokenSource(src)
+	c.New().Get()
+	resp, _ := c.New().Get()
+	data, _ := resp.AsString()
+	assert.Equal("Bearer token-2", data)
+}
+
+func TestTokenRefreshOnUnauthorized(t *testing.T) {
+	assert := assert.New(t)
+
+	bodies := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := make([]byte, 64)
+		n, _ := r.Body.Read(data)
+		bodies = append(bodies, string(data[:n]))
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			http.Error(w, "token revoked", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "welcome")
+	}))
+	defer ts.Close()
+
+	tokens := []string{"revoked", "fresh"}
+	src := gohttp.TokenSourceFunc(func() (*gohttp.Token, error) {
+		token := tokens[0]
+		tokens = tokens[1:]
+		return &gohttp.Token{AccessToken: token, Expiry: time.Now().Add(time.Hour)}, nil
+	})
+
+	resp, err := gohttp.New().TokenSource(src).JSON(`{"id":1}`).Post(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	data, _ := resp.AsString()
+	assert.Equal("welcome", data)
+	assert.Equal([]string{`{"id":1}`, `{"id":1}`}, bodies, "request should be replayed with full body")
+}
+
+func TestTokenRefreshOnlyOnce(t *testing.T) {
+	assert := assert.New(t)
+
+	tried := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tried++
+		http.Error(w, "go away", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	resp, err := gohttp.New().BearerToken("static").Get(ts.URL)
+	assert.NoError(err)
+	assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(1, tried, "static token should not be replayed")
+}
+
+func TestTokenSourceError(t *testing.T) {
+	assert := assert.New(t)
+
+	sent := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = true
+	}))
+	defer ts.Close()
+
+	tokenErr := errors.New("vault is sealed")
+	_, err := gohttp.New().TokenSource(gohttp.TokenSourceFunc(func() (*gohttp.Token, error) {
+		return nil, tokenErr
+	})).Get(ts.URL)
+	assert.Equal(tokenErr, err)
+	assert.False(sent)
+}
@@ -0,0 +1,64 @@
+package gohttp
+
+import "net/http"
+
+// Middleware wraps a `http.RoundTripper` with extra behavior, like tracing, metrics or caching.
+// It returns a new RoundTripper that usually calls the wrapped one to send request.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as `http.RoundTripper`.
+// It is handy to write middlewares and test fakes:
+//    gohttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+//        return &http.Response{StatusCode: 200, Body: http.NoBody, Request: req}, nil
+//    })
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Use appends middlewares to the transport chain of the client.
+// Middlewares registered first are the outermost ones: they see the request first,
+// and the response last. Clients cloned with `New` inherit the middlewares.
+//
+// Usage:
+//    gohttp.New().Use(tracing, metrics).Get(url)
+//    // request flows: tracing -> metrics -> transport
+func (c *Client) Use(middlewares ...Middleware) *Client {
+	for _, m := range middlewares {
+		if m != nil {
+			c.middlewares = append(c.middlewares, m)
+		}
+	}
+	return c
+}
+
+// Transport replaces the base transport which actually sends requests, middlewares added by `Use`
+// are still applied around it.
+//
+// If `rt` is a `*http.Transport`, connection settings like proxy and TLS handshake timeout are
+// applied to it as usual. Other RoundTripper implementations are used as is, and those settings
+// are their own responsibility.
+func (c *Client) Transport(rt http.RoundTripper) *Client {
+	if t, ok := rt.(*http.Transport); ok {
+		c.transport = t
+		c.baseTransport = nil
+	} else if rt != nil {
+		c.baseTransport = rt
+	}
+	return c
+}
+
+// roundTripper builds the transport chain: middlewares wrapping the base transport.
+// `transport` is the configured `*http.Transport`, it is used unless a custom RoundTripper is set.
+func (c *Client) roundTripper(transport *http.Transport) http.RoundTripper {
+	var rt http.RoundTripper = transport
+	if c.baseTransport != nil {
+		rt = c.baseTransport
+	}
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		rt = c.middlewares[i](rt)
+	}
+	return rt
+}
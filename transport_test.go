@@ -0,0 +1,77 @@
+package gohttp_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// recordMiddleware appends name to calls when request goes through it, and name + "-done" on the way back.
+func recordMiddleware(name string, calls *[]string) gohttp.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return gohttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*calls = append(*calls, name)
+			resp, err := next.RoundTrip(req)
+			*calls = append(*calls, name+"-done")
+			return resp, err
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	calls := []string{}
+	_, err := gohttp.New().
+		Use(recordMiddleware("tracing", &calls)).
+		Use(recordMiddleware("metrics", &calls)).
+		Get(ts.URL)
+	assert.NoError(err)
+	assert.Equal([]string{"tracing", "metrics", "metrics-done", "tracing-done"}, calls)
+}
+
+func TestMiddlewareInheritedByClone(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	calls := []string{}
+	base := gohttp.New().URL(ts.URL).Use(recordMiddleware("base", &calls))
+	base.New().Use(recordMiddleware("clone", &calls)).Get()
+	assert.Equal([]string{"base", "clone", "clone-done", "base-done"}, calls)
+
+	calls = calls[:0]
+	base.New().Get()
+	assert.Equal([]string{"base", "base-done"}, calls, "middleware added to clone should not affect base")
+}
+
+func TestCustomTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := gohttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusTeapot,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader("fake " + req.URL.Path)),
+			Request:    req,
+		}, nil
+	})
+
+	calls := []string{}
+	resp, err := gohttp.New().Transport(fake).Use(recordMiddleware("m", &calls)).Get("http://example.com/hello")
+	assert.NoError(err)
+	assert.Equal(http.StatusTeapot, resp.StatusCode)
+	data, _ := resp.AsString()
+	assert.Equal("fake /hello", data)
+	assert.Equal([]string{"m", "m-done"}, calls, "middlewares should wrap custom transport")
+}
@@ -0,0 +1,32 @@
+package gohttp
+
+import (
+	"context"
+	"net"
+)
+
+// unixSocketHost is the host of requests to unix socket, if their url has none.
+const unixSocketHost = "localhost"
+
+// UnixSocket sends requests to the unix domain socket at path, instead of the host in url.
+// Url, path, query and body are set as usual, the host of url is only sent in `Host` header,
+// and can be left out: `/containers/json` is sent as `http://localhost/containers/json`.
+// Proxies are not used for unix sockets.
+//
+// Clients cloned by `New` can talk to different sockets, connections are kept for each socket.
+// Empty path turns it off.
+//
+// Usage:
+//    docker := gohttp.New().UnixSocket("/var/run/docker.sock").URL("http://docker/v1.43")
+//    resp, err := docker.New().Path("/containers/json").Query("all", "true").Get()
+func (c *Client) UnixSocket(path string) *Client {
+	c.dialOptions().unixSocket = path
+	return c
+}
+
+// dialUnix returns the dial function connecting to unix socket at path whatever the address is.
+func dialUnix(path string, forward dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return forward(ctx, "unix", path)
+	}
+}
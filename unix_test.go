@@ -0,0 +1,80 @@
+package gohttp_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cizixs/gohttp"
+)
+
+// newUnixServer starts a server listening on unix socket `dir/name.sock`, it echoes its name,
+// and the host, path, query and body of requests.
+func newUnixServer(t *testing.T, dir, name string) (*httptest.Server, string) {
+	path := filepath.Join(dir, name+".sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s %s %s", name, r.Host, r.URL.Path, r.URL.RawQuery, body)
+	}))
+	ts.Listener = ln
+	ts.Start()
+	return ts, path
+}
+
+func TestUnixSocket(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	docker, dockerSocket := newUnixServer(t, dir, "docker")
+	defer docker.Close()
+	sidecar, sidecarSocket := newUnixServer(t, dir, "sidecar")
+	defer sidecar.Close()
+
+	c := gohttp.New().UnixSocket(dockerSocket).URL("http://docker/v1.43")
+
+	resp, err := c.New().Path("/containers/json").Query("all", "true").Get()
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("docker docker /v1.43/containers/json all=true ", data)
+	}
+
+	resp, err = c.New().Path("/containers/create").JSON(`{"Image":"alpine"}`).Post()
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal(`docker docker /v1.43/containers/create  {"Image":"alpine"}`, data)
+	}
+
+	// host can be left out
+	resp, err = gohttp.New().UnixSocket(sidecarSocket).Get("/healthz")
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("sidecar localhost /healthz  ", data)
+	}
+
+	// clones target their own sockets
+	resp, err = c.New().UnixSocket(sidecarSocket).Path("/ready").Get()
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("sidecar docker /v1.43/ready  ", data)
+	}
+	resp, err = c.New().Path("/info").Get()
+	if assert.NoError(err) {
+		data, _ := resp.AsString()
+		assert.Equal("docker docker /v1.43/info  ", data)
+	}
+
+	_, err = gohttp.New().UnixSocket(filepath.Join(dir, "missing.sock")).Get("/")
+	assert.Error(err)
+}